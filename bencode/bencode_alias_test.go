@@ -0,0 +1,251 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	bencode2 "github.com/IncSW/go-bencode"
+	bencode1 "github.com/cristalhq/bencode"
+)
+
+// aliasMarker never occurs in unmarshalBenchData, so finding it in a
+// decoded value means the value still points into the input buffer.
+const aliasMarker = 'X'
+
+// aliasState is what TestAliasing finds for a decoder.
+type aliasState string
+
+const (
+	aliasNo      aliasState = "no"
+	aliasYes     aliasState = "yes"
+	aliasUnknown aliasState = "unknown" // the result is nil or holds values the probe cannot look into
+	aliasNA      aliasState = "n/a"     // the library has no decoder
+)
+
+// aliasDecoder is a decoder probed by TestAliasing.
+type aliasDecoder struct {
+	name   string
+	decode func(data []byte) (interface{}, error)
+}
+
+// aliasDecoders are the generic decoders of bencodeLibs and the reader mode
+// of cristalhq, the pair measured by Benchmark_cristalhq_UnmarshalReused and
+// Benchmark_cristalhq_UnmarshalReaderReused.
+func aliasDecoders() []aliasDecoder {
+	decoders := []aliasDecoder{
+		{
+			name: "cristalhq/reader",
+			decode: func(data []byte) (interface{}, error) {
+				var res interface{}
+				err := bencode1.NewDecoder(bytes.NewReader(data)).Decode(&res)
+				return res, err
+			},
+		},
+	}
+	for _, lib := range bencodeLibs {
+		decoders = append(decoders, libAliasDecoder(lib))
+	}
+	return decoders
+}
+
+// libAliasDecoder probes the generic decoder of lib.
+func libAliasDecoder(lib bencodeLib) aliasDecoder {
+	return aliasDecoder{
+		name: lib.name,
+		decode: func(data []byte) (interface{}, error) {
+			return safeDecode(lib, data)
+		},
+	}
+}
+
+// aliasExpected is the zero-copy state of every decoder TestAliasing has
+// been run against. Decoders that read from an io.Reader have to copy. The
+// probe does not need a working benchmark, so the libraries whose
+// benchmarks are skipped are probed as well.
+var aliasExpected = map[string]aliasState{
+	"IncSW":      aliasYes,
+	"Zeebo":      aliasNo,
+	"Marksamman": aliasNo,
+	"Jackpal":    aliasNo,
+	"Chihaya":    aliasNo,
+	"Anacrolix":  aliasNo,
+	"Stints":     aliasNA,
+	"Lwch":       aliasNo,
+	"Cuberat":    aliasNo,
+}
+
+// aliasFlatData is unmarshalBenchData without announce-list, for the
+// decoders that reject a list in a list.
+var aliasFlatData = []byte("d4:infod6:lengthi170917888e12:piece lengthi262144e4:name30:debian-8.8.0-arm64-netinst.isoe8:announce38:udp://tracker.publicbt.com:80/announce7:comment33:Debian CD from cdimage.debian.orge")
+
+// TestAliasing decodes a private copy of unmarshalBenchData, overwrites
+// that copy and looks for the overwritten bytes in the decoded result.
+// A decoder that rejects the data is probed with aliasFlatData instead.
+// A decoder that does not match aliasExpected fails, one without an entry
+// is only logged, so its state can be recorded.
+// Run with -v to get the table.
+func TestAliasing(t *testing.T) {
+	t.Logf("%-16s %s", "library", "zero-copy")
+	for _, dec := range aliasDecoders() {
+		got, flat, err := probeAliases(dec)
+		switch {
+		case errors.Is(err, errUnsupported):
+			got = aliasNA
+		case err != nil:
+			t.Logf("%-16s error: %v", dec.name, err)
+			t.Errorf("%s: %v", dec.name, err)
+			continue
+		}
+		if flat {
+			t.Logf("%-16s %s (aliasFlatData)", dec.name, got)
+		} else {
+			t.Logf("%-16s %s", dec.name, got)
+		}
+
+		want, ok := aliasExpected[dec.name]
+		switch {
+		case !ok:
+			t.Logf("%-16s not in aliasExpected yet", dec.name)
+		case got != want:
+			t.Errorf("%s: zero-copy is %s, want %s", dec.name, got, want)
+		}
+	}
+}
+
+// probeAliases is decodeAliases on unmarshalBenchData, or on aliasFlatData
+// when the decoder rejects the former, which flat reports.
+func probeAliases(dec aliasDecoder) (state aliasState, flat bool, err error) {
+	state, err = decodeAliases(dec, unmarshalBenchData)
+	if err == nil || errors.Is(err, errUnsupported) {
+		return state, false, err
+	}
+	state, err = decodeAliases(dec, aliasFlatData)
+	return state, true, err
+}
+
+// decodeAliases reports whether any string in the decoded data
+// shares memory with the input. A nil result tells nothing.
+func decodeAliases(dec aliasDecoder, data []byte) (aliasState, error) {
+	buf := append([]byte(nil), data...)
+	res, err := dec.decode(buf)
+	if err != nil {
+		return aliasUnknown, err
+	}
+	if res == nil {
+		return aliasUnknown, nil
+	}
+	for i := range buf {
+		buf[i] = aliasMarker
+	}
+	return hasAliasMarker(reflect.ValueOf(res)), nil
+}
+
+// hasAliasMarker walks v for strings and byte slices. Finding the marker
+// anywhere is a yes, a value it cannot look into makes a no unknown.
+func hasAliasMarker(v reflect.Value) aliasState {
+	state := aliasNo
+	merge := func(s aliasState) bool {
+		if s == aliasYes || state == aliasNo {
+			state = s
+		}
+		return state == aliasYes
+	}
+
+	switch v.Kind() {
+	case reflect.Invalid, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+	case reflect.String:
+		if strings.IndexByte(v.String(), aliasMarker) >= 0 {
+			return aliasYes
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if bytes.IndexByte(v.Bytes(), aliasMarker) >= 0 {
+				return aliasYes
+			}
+			break
+		}
+		fallthrough
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if merge(hasAliasMarker(v.Index(i))) {
+				break
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if merge(hasAliasMarker(iter.Key())) || merge(hasAliasMarker(iter.Value())) {
+				break
+			}
+		}
+	case reflect.Interface, reflect.Ptr:
+		if !v.IsNil() {
+			return hasAliasMarker(v.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if merge(hasAliasMarker(v.Field(i))) {
+				break
+			}
+		}
+	default:
+		return aliasUnknown
+	}
+	return state
+}
+
+// A zero-copy decoder is only safe on a buffer that is not reused, so a
+// caller that reuses it has to decode from a copy. The pairs below are
+// these two modes for the decoders that alias the input.
+
+// benchAliasModes decodes a buffer that is kept for the whole run, straight
+// or from a fresh copy of it.
+func benchAliasModes(b *testing.B, copyInput bool, decode func(data []byte) (interface{}, error)) {
+	buf := append([]byte(nil), unmarshalBenchData...)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		data := buf
+		if copyInput {
+			data = append([]byte(nil), buf...)
+		}
+		res, err := decode(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if res == nil {
+			b.Fatal("is nil")
+		}
+	}
+}
+
+func decodeCristalhq(data []byte) (interface{}, error) {
+	var res interface{}
+	err := bencode1.NewDecodeBytes(data).Decode(&res)
+	return res, err
+}
+
+func decodeIncSW(data []byte) (interface{}, error) {
+	return bencode2.Unmarshal(data)
+}
+
+func Benchmark_cristalhq_UnmarshalZeroCopyFresh(b *testing.B) {
+	benchAliasModes(b, false, decodeCristalhq)
+}
+
+func Benchmark_cristalhq_UnmarshalCopiedFresh(b *testing.B) {
+	benchAliasModes(b, true, decodeCristalhq)
+}
+
+func Benchmark_IncSW_UnmarshalZeroCopyFresh(b *testing.B) {
+	benchAliasModes(b, false, decodeIncSW)
+}
+
+func Benchmark_IncSW_UnmarshalCopiedFresh(b *testing.B) {
+	benchAliasModes(b, true, decodeIncSW)
+}
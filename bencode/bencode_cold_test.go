@@ -0,0 +1,228 @@
+package bencode
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+var coldRuns = flag.Int("cold", 0, "number of fresh processes for every cold-start measurement, 0 skips them")
+
+// coldEnv tells a spawned test process which measurement to make,
+// its value is "<library>/<op>".
+const coldEnv = "BENCODE_COLD"
+
+// coldTypeCounts are the points at which the type cache growth is reported.
+var coldTypeCounts = []int{1, 10, 100, 1000}
+
+// TestColdStart times the first Marshal and Unmarshal of a struct type
+// for every library. Each measurement runs in a fresh process, so none of
+// the reflection caches are warm, the second call shows the steady state.
+// It also decodes into many distinct struct types to show how the caches
+// grow. Run with:
+//
+//	go test -run TestColdStart -cold 10 -v
+func TestColdStart(t *testing.T) {
+	if *coldRuns <= 0 {
+		t.Skip("enable with -cold N")
+	}
+
+	t.Logf("%-12s %-10s %12s %12s", "library", "op", "first", "second")
+	for _, lib := range bencodeLibs {
+		for _, op := range []string{"marshal", "unmarshal"} {
+			if (op == "marshal" && lib.marshal == nil) || (op == "unmarshal" && lib.unmarshal == nil) {
+				t.Logf("%-12s %-10s n/a", lib.name, op)
+				continue
+			}
+
+			var first, second []time.Duration
+			var failure string
+			for i := 0; i < *coldRuns; i++ {
+				lines, err := runColdChild(lib.name, op)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var a, b int64
+				if _, err := fmt.Sscanf(lines[0], "cold %d %d", &a, &b); err != nil {
+					failure = strings.TrimPrefix(lines[0], "cold ")
+					break
+				}
+				first = append(first, time.Duration(a))
+				second = append(second, time.Duration(b))
+			}
+			if failure != "" {
+				t.Logf("%-12s %-10s %s", lib.name, op, failure)
+				continue
+			}
+			t.Logf("%-12s %-10s %12v %12v", lib.name, op, median(first), median(second))
+		}
+	}
+
+	t.Logf("%-12s %6s %12s %12s", "library", "types", "per decode", "heap")
+	for _, lib := range bencodeLibs {
+		if lib.unmarshal == nil {
+			continue
+		}
+		lines, err := runColdChild(lib.name, "types")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range lines {
+			var n int
+			var perDecode time.Duration
+			var heap int64
+			if _, err := fmt.Sscanf(line, "types %d %d %d", &n, &perDecode, &heap); err != nil {
+				t.Logf("%-12s %s", lib.name, strings.TrimPrefix(line, "types "))
+				break
+			}
+			t.Logf("%-12s %6d %12v %11dB", lib.name, n, perDecode, heap)
+		}
+	}
+}
+
+// TestColdStartChild makes a single measurement in a process spawned
+// by TestColdStart and prints the result to stdout.
+func TestColdStartChild(t *testing.T) {
+	spec := os.Getenv(coldEnv)
+	if spec == "" {
+		t.Skip("runs only in a process spawned by TestColdStart")
+	}
+	parts := strings.SplitN(spec, "/", 2)
+	lib, ok := findLib(parts[0])
+	if !ok || len(parts) != 2 {
+		t.Fatalf("bad %s=%q", coldEnv, spec)
+	}
+
+	switch op := parts[1]; op {
+	case "marshal":
+		printColdTimes(func() error {
+			_, err := safeMarshal(lib, torrentBenchData)
+			return err
+		})
+	case "unmarshal":
+		printColdTimes(func() error {
+			var res torrentFile
+			return safeUnmarshal(lib, unmarshalBenchData, &res)
+		})
+	case "types":
+		printTypeGrowth(lib)
+	default:
+		t.Fatalf("unknown op %q", op)
+	}
+}
+
+func runColdChild(name, op string) ([]string, error) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestColdStartChild$")
+	cmd.Env = append(os.Environ(), coldEnv+"="+name+"/"+op)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s/%s: %v\n%s", name, op, err, out)
+	}
+
+	var lines []string
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "cold ") || strings.HasPrefix(line, "types ") {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%s/%s: no result in output:\n%s", name, op, out)
+	}
+	return lines, nil
+}
+
+func printColdTimes(fn func() error) {
+	start := time.Now()
+	err := fn()
+	first := time.Since(start)
+	if err != nil {
+		fmt.Printf("cold error: %v\n", err)
+		return
+	}
+
+	start = time.Now()
+	err = fn()
+	second := time.Since(start)
+	if err != nil {
+		fmt.Printf("cold error on second call: %v\n", err)
+		return
+	}
+	fmt.Printf("cold %d %d\n", first, second)
+}
+
+// printTypeGrowth decodes unmarshalBenchData into distinct struct types and
+// reports the average decode time and the retained heap at each count.
+func printTypeGrowth(lib bencodeLib) {
+	last := coldTypeCounts[len(coldTypeCounts)-1]
+	types := make([]reflect.Type, last)
+	for i := range types {
+		types[i] = distinctTorrentType(i)
+	}
+
+	var ms runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&ms)
+	baseHeap := ms.HeapAlloc
+
+	var elapsed time.Duration
+	done := 0
+	for _, count := range coldTypeCounts {
+		batch := count - done
+		elapsed = 0
+		for ; done < count; done++ {
+			v := reflect.New(types[done]).Interface()
+			start := time.Now()
+			err := safeUnmarshal(lib, unmarshalBenchData, v)
+			elapsed += time.Since(start)
+			if err != nil {
+				fmt.Printf("types error: %v\n", err)
+				return
+			}
+		}
+
+		runtime.GC()
+		runtime.ReadMemStats(&ms)
+		fmt.Printf("types %d %d %d\n", count, elapsed/time.Duration(batch), int64(ms.HeapAlloc)-int64(baseHeap))
+	}
+}
+
+// distinctTorrentType returns a struct type that is new to every
+// library cache but still matches fields of unmarshalBenchData.
+func distinctTorrentType(i int) reflect.Type {
+	str := reflect.TypeOf("")
+	return reflect.StructOf([]reflect.StructField{
+		{Name: "Announce", Type: str, Tag: `bencode:"announce"`},
+		{Name: "Comment", Type: str, Tag: `bencode:"comment"`},
+		{
+			Name: fmt.Sprintf("Extra%d", i),
+			Type: str,
+			Tag:  reflect.StructTag(fmt.Sprintf(`bencode:"extra-%d"`, i)),
+		},
+	})
+}
+
+func findLib(name string) (bencodeLib, bool) {
+	for _, lib := range bencodeLibs {
+		if lib.name == name {
+			return lib, true
+		}
+	}
+	return bencodeLib{}, false
+}
+
+func median(ds []time.Duration) time.Duration {
+	sorted := append([]time.Duration(nil), ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
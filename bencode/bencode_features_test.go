@@ -0,0 +1,283 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var featuresOut = flag.String("features", "", "write the feature matrix as markdown to this file")
+
+// featureProbe checks one behaviour of a library. The result is "yes" or
+// "no", "n/a" when the library lacks the operation, "not probed" when the
+// probe does not know how to ask the library, or a short note.
+type featureProbe struct {
+	name string
+	run  func(lib bencodeLib) string
+}
+
+var featureProbes = []featureProbe{
+	{"omitempty", probeOmitEmpty},
+	{"Marshaler", probeMarshaler},
+	{"Unmarshaler", probeUnmarshaler},
+	{"pointers", probePointers},
+	{"embedded", probeEmbedded},
+	{"rejects float64", probeRejects(struct {
+		F float64 `bencode:"f"`
+	}{F: 1.5})},
+	{"rejects bool", probeRejects(struct {
+		B bool `bencode:"b"`
+	}{B: true})},
+	{"zero-copy", probeZeroCopy},
+}
+
+// TestFeatureMatrix runs every probe against every library.
+// Run with -v to see the matrix or with -features to write it:
+//
+//	go test -run TestFeatureMatrix -features features.md
+func TestFeatureMatrix(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("| library |")
+	for _, p := range featureProbes {
+		buf.WriteString(" " + p.name + " |")
+	}
+	buf.WriteString("\n|---|" + strings.Repeat("---|", len(featureProbes)) + "\n")
+
+	for _, lib := range bencodeLibs {
+		buf.WriteString("| " + lib.name + " |")
+		for _, p := range featureProbes {
+			buf.WriteString(" " + p.run(lib) + " |")
+		}
+		buf.WriteString("\n")
+	}
+
+	t.Log("\n" + buf.String())
+	if *featuresOut != "" {
+		if err := ioutil.WriteFile(*featuresOut, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// customDoc is a dictionary with a single value "v" of a custom type,
+// called reports whether the custom unmarshal method ran.
+type customDoc interface {
+	called() bool
+}
+
+// customDocs gives every library a document whose value implements that
+// library's own Marshaler and Unmarshaler. A library without an entry is
+// not probed.
+var customDocs = map[string]func() customDoc{
+	"cristalhq": func() customDoc { return &bytesCustomDoc{} },
+	"Zeebo":     func() customDoc { return &bytesCustomDoc{} },
+	"Anacrolix": func() customDoc { return &bytesCustomDoc{} },
+	"Tumdum":    func() customDoc { return &bencodingCustomDoc{} },
+}
+
+// customMapDocs are the documents of the libraries that encode maps but not
+// structs, their Marshaler is probed through a map value.
+var customMapDocs = map[string]interface{}{
+	"Chihaya": map[string]interface{}{"v": bytesCustomValue{}},
+}
+
+// noCustomInterfaces are the libraries that have no Marshaler
+// or Unmarshaler interface at all.
+var noCustomInterfaces = map[string]bool{
+	"Jackpal": true,
+}
+
+// bytesCustomValue has MarshalBencode() ([]byte, error)
+// and UnmarshalBencode([]byte) error.
+type bytesCustomValue struct {
+	called bool
+}
+
+func (bytesCustomValue) MarshalBencode() ([]byte, error) {
+	return []byte("6:custom"), nil
+}
+
+func (c *bytesCustomValue) UnmarshalBencode(data []byte) error {
+	c.called = true
+	return nil
+}
+
+type bytesCustomDoc struct {
+	V bytesCustomValue `bencode:"v"`
+}
+
+func (d *bytesCustomDoc) called() bool { return d.V.called }
+
+// bencodingCustomValue has MarshalBencoding() ([]byte, error)
+// and UnmarshalBencoding([]byte) error.
+type bencodingCustomValue struct {
+	called bool
+}
+
+func (bencodingCustomValue) MarshalBencoding() ([]byte, error) {
+	return []byte("6:custom"), nil
+}
+
+func (c *bencodingCustomValue) UnmarshalBencoding(data []byte) error {
+	c.called = true
+	return nil
+}
+
+type bencodingCustomDoc struct {
+	V bencodingCustomValue `bencode:"v"`
+}
+
+func (d *bencodingCustomDoc) called() bool { return d.V.called }
+
+type embeddedPart struct {
+	A string `bencode:"a"`
+}
+
+func probeOmitEmpty(lib bencodeLib) string {
+	return probeMarshal(lib, struct {
+		A string `bencode:"a,omitempty"`
+		B string `bencode:"b"`
+	}{B: "x"}, "d1:b1:xe")
+}
+
+// probeMarshaler checks the library's own Marshaler interface,
+// see customDocs.
+func probeMarshaler(lib bencodeLib) string {
+	if doc, ok := customMapDocs[lib.name]; ok {
+		return probeMarshal(lib, doc, "d1:v6:custome")
+	}
+	newDoc, ok := customDocs[lib.name]
+	if !ok {
+		return probeMissing(lib, lib.marshal != nil)
+	}
+	return probeMarshal(lib, newDoc(), "d1:v6:custome")
+}
+
+// probeUnmarshaler is probeMarshaler for the Unmarshaler interface.
+func probeUnmarshaler(lib bencodeLib) string {
+	newDoc, ok := customDocs[lib.name]
+	if !ok {
+		return probeMissing(lib, lib.unmarshal != nil)
+	}
+	doc := newDoc()
+	return probeUnmarshal(lib, "d1:v3:abce", doc, doc.called)
+}
+
+// probeMissing is the result for a library without an entry in
+// customDocs, "n/a" when it lacks the operation, "no" when it is
+// listed in noCustomInterfaces.
+func probeMissing(lib bencodeLib, hasOp bool) string {
+	switch {
+	case !hasOp:
+		return "n/a"
+	case noCustomInterfaces[lib.name]:
+		return "no"
+	default:
+		return "not probed"
+	}
+}
+
+func probePointers(lib bencodeLib) string {
+	s := "x"
+	enc := probeMarshal(lib, struct {
+		P *string `bencode:"p"`
+	}{P: &s}, "d1:p1:xe")
+
+	var res struct {
+		P *string `bencode:"p"`
+	}
+	dec := probeUnmarshal(lib, "d1:p1:xe", &res, func() bool {
+		return res.P != nil && *res.P == "x"
+	})
+	return enc + "/" + dec
+}
+
+func probeEmbedded(lib bencodeLib) string {
+	enc := probeMarshal(lib, struct {
+		embeddedPart
+		C string `bencode:"c"`
+	}{embeddedPart{A: "x"}, "y"}, "d1:a1:x1:c1:ye")
+
+	var res struct {
+		embeddedPart
+		C string `bencode:"c"`
+	}
+	dec := probeUnmarshal(lib, "d1:a1:x1:c1:ye", &res, func() bool {
+		return res.A == "x" && res.C == "y"
+	})
+	return enc + "/" + dec
+}
+
+// probeRejects reports "yes" when marshaling v fails,
+// otherwise it shows what the library produced, quoted.
+func probeRejects(v interface{}) func(lib bencodeLib) string {
+	return func(lib bencodeLib) string {
+		data, err := safeMarshal(lib, v)
+		switch {
+		case errors.Is(err, errUnsupported):
+			return "n/a"
+		case err != nil:
+			return "yes"
+		default:
+			return "no " + markdownCell(strconv.Quote(string(data)))
+		}
+	}
+}
+
+// probeZeroCopy is the TestAliasing probe.
+func probeZeroCopy(lib bencodeLib) string {
+	state, _, err := probeAliases(libAliasDecoder(lib))
+	switch {
+	case errors.Is(err, errUnsupported):
+		return string(aliasNA)
+	case err != nil:
+		return "err"
+	default:
+		return string(state)
+	}
+}
+
+func probeMarshal(lib bencodeLib, v interface{}, want string) string {
+	data, err := safeMarshal(lib, v)
+	switch {
+	case errors.Is(err, errUnsupported):
+		return "n/a"
+	case err != nil:
+		return "err"
+	case string(data) == want:
+		return "yes"
+	default:
+		return "no"
+	}
+}
+
+func probeUnmarshal(lib bencodeLib, data string, v interface{}, ok func() bool) string {
+	err := safeUnmarshal(lib, []byte(data), v)
+	switch {
+	case errors.Is(err, errUnsupported):
+		return "n/a"
+	case err != nil:
+		return "err"
+	case ok():
+		return "yes"
+	default:
+		return "no"
+	}
+}
+
+// markdownCell escapes the characters that end a cell
+// or start inline markup in a markdown table.
+func markdownCell(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		`|`, `\|`,
+		"`", "\\`",
+		`*`, `\*`,
+		`_`, `\_`,
+		`<`, `&lt;`,
+	).Replace(s)
+}
@@ -0,0 +1,213 @@
+package bencode
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+
+	bencode8 "github.com/anacrolix/torrent/bencode"
+	bencode1 "github.com/cristalhq/bencode"
+	bencode3 "github.com/zeebo/bencode"
+)
+
+// torrentFile is the typed form of unmarshalBenchData,
+// so every format below encodes exactly the same document.
+type torrentFile struct {
+	Announce     string      `bencode:"announce" json:"announce"`
+	AnnounceList [][]string  `bencode:"announce-list" json:"announce-list"`
+	Comment      string      `bencode:"comment" json:"comment"`
+	Info         torrentInfo `bencode:"info" json:"info"`
+}
+
+type torrentInfo struct {
+	Length      int64  `bencode:"length" json:"length"`
+	Name        string `bencode:"name" json:"name"`
+	PieceLength int64  `bencode:"piece length" json:"piece length"`
+}
+
+var torrentBenchData = torrentFile{
+	Announce: "udp://tracker.publicbt.com:80/announce",
+	AnnounceList: [][]string{
+		{"udp://tracker.publicbt.com:80/announce"},
+		{"udp://tracker.openbittorrent.com:80/announce"},
+	},
+	Comment: "Debian CD from cdimage.debian.org",
+	Info: torrentInfo{
+		Length:      170917888,
+		Name:        "debian-8.8.0-arm64-netinst.iso",
+		PieceLength: 262144,
+	},
+}
+
+func newTorrentTarget() interface{} {
+	return new(torrentFile)
+}
+
+// reportDocSize reports the encoded size of the document
+// and lets the benchmark print MB/s for it.
+func reportDocSize(b *testing.B, data []byte) {
+	b.SetBytes(int64(len(data)))
+	b.ReportMetric(float64(len(data)), "B/doc")
+}
+
+func mustMarshalJSON(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// mustMarshalGob encodes v with a fresh encoder, so the type
+// description is included as it is for a standalone document.
+func mustMarshalGob(v interface{}) []byte {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+func Benchmark_JSON_Marshal(b *testing.B) {
+	reportDocSize(b, mustMarshalJSON(torrentBenchData))
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		_, err := json.Marshal(torrentBenchData)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_JSON_UnmarshalFresh(b *testing.B) {
+	data := mustMarshalJSON(torrentBenchData)
+	reportDocSize(b, data)
+	benchDecodeInto(b, true, data, newTorrentTarget, func(data []byte, dst interface{}) error {
+		return json.Unmarshal(data, dst)
+	})
+}
+
+func Benchmark_JSON_UnmarshalReused(b *testing.B) {
+	data := mustMarshalJSON(torrentBenchData)
+	reportDocSize(b, data)
+	benchDecodeInto(b, false, data, newTorrentTarget, func(data []byte, dst interface{}) error {
+		return json.Unmarshal(data, dst)
+	})
+}
+
+func Benchmark_Gob_Marshal(b *testing.B) {
+	reportDocSize(b, mustMarshalGob(torrentBenchData))
+	buf := bytes.NewBuffer(make([]byte, 0, 1<<12))
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		buf.Reset()
+		err := gob.NewEncoder(buf).Encode(torrentBenchData)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_Gob_UnmarshalFresh(b *testing.B) {
+	data := mustMarshalGob(torrentBenchData)
+	reportDocSize(b, data)
+	benchDecodeInto(b, true, data, newTorrentTarget, func(data []byte, dst interface{}) error {
+		return gob.NewDecoder(bytes.NewReader(data)).Decode(dst)
+	})
+}
+
+func Benchmark_Gob_UnmarshalReused(b *testing.B) {
+	data := mustMarshalGob(torrentBenchData)
+	reportDocSize(b, data)
+	benchDecodeInto(b, false, data, newTorrentTarget, func(data []byte, dst interface{}) error {
+		return gob.NewDecoder(bytes.NewReader(data)).Decode(dst)
+	})
+}
+
+func Benchmark_cristalhq_MarshalStruct(b *testing.B) {
+	data, err := bencode1.Marshal(torrentBenchData)
+	if err != nil {
+		b.Fatal(err)
+	}
+	reportDocSize(b, data)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		_, err := bencode1.Marshal(torrentBenchData)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_cristalhq_UnmarshalStructFresh(b *testing.B) {
+	reportDocSize(b, unmarshalBenchData)
+	benchDecodeInto(b, true, unmarshalBenchData, newTorrentTarget, func(data []byte, dst interface{}) error {
+		return bencode1.NewDecodeBytes(data).Decode(dst)
+	})
+}
+
+func Benchmark_cristalhq_UnmarshalStructReused(b *testing.B) {
+	reportDocSize(b, unmarshalBenchData)
+	benchDecodeInto(b, false, unmarshalBenchData, newTorrentTarget, func(data []byte, dst interface{}) error {
+		return bencode1.NewDecodeBytes(data).Decode(dst)
+	})
+}
+
+func Benchmark_ZeeboBencode_MarshalStruct(b *testing.B) {
+	data, err := bencode3.EncodeBytes(torrentBenchData)
+	if err != nil {
+		b.Fatal(err)
+	}
+	reportDocSize(b, data)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		_, err := bencode3.EncodeBytes(torrentBenchData)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_ZeeboBencode_UnmarshalStructFresh(b *testing.B) {
+	reportDocSize(b, unmarshalBenchData)
+	benchDecodeInto(b, true, unmarshalBenchData, newTorrentTarget, func(data []byte, dst interface{}) error {
+		return bencode3.DecodeBytes(data, dst)
+	})
+}
+
+func Benchmark_ZeeboBencode_UnmarshalStructReused(b *testing.B) {
+	reportDocSize(b, unmarshalBenchData)
+	benchDecodeInto(b, false, unmarshalBenchData, newTorrentTarget, func(data []byte, dst interface{}) error {
+		return bencode3.DecodeBytes(data, dst)
+	})
+}
+
+func Benchmark_AnacrolixTorrent_MarshalStruct(b *testing.B) {
+	data, err := bencode8.Marshal(torrentBenchData)
+	if err != nil {
+		b.Fatal(err)
+	}
+	reportDocSize(b, data)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		_, err := bencode8.Marshal(torrentBenchData)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_AnacrolixTorrent_UnmarshalStructFresh(b *testing.B) {
+	reportDocSize(b, unmarshalBenchData)
+	benchDecodeInto(b, true, unmarshalBenchData, newTorrentTarget, func(data []byte, dst interface{}) error {
+		return bencode8.Unmarshal(data, dst)
+	})
+}
+
+func Benchmark_AnacrolixTorrent_UnmarshalStructReused(b *testing.B) {
+	reportDocSize(b, unmarshalBenchData)
+	benchDecodeInto(b, false, unmarshalBenchData, newTorrentTarget, func(data []byte, dst interface{}) error {
+		return bencode8.Unmarshal(data, dst)
+	})
+}
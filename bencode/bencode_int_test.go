@@ -0,0 +1,276 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// intCases are integers around the limits of Go integer types.
+// Real metainfo has lengths above 2^32 and rarely values beyond int64.
+var intCases = []struct {
+	name  string
+	value string
+}{
+	{"small", "170917888"},
+	{"2^32+1", "4294967297"},
+	{"max64", "9223372036854775807"},
+	{"min64", "-9223372036854775808"},
+	{"max64+1", "9223372036854775808"},
+	{"maxu64", "18446744073709551615"},
+	{"2^100", "1267650600228229401496703205376"},
+}
+
+// errIntShape is returned when the decoded value is not an integer
+// in any form, which is neither a value nor a rejection of it.
+var errIntShape = errors.New("unexpected shape")
+
+// intTargets are the destinations the integer is decoded into,
+// "generic" is the library's own representation.
+var intTargets = []struct {
+	name string
+	typ  reflect.Type
+}{
+	{"generic", nil},
+	{"int", reflect.TypeOf(int(0))},
+	{"int64", reflect.TypeOf(int64(0))},
+	{"uint64", reflect.TypeOf(uint64(0))},
+	{"*big.Int", reflect.TypeOf((*big.Int)(nil))},
+}
+
+// intKnown are the silently wrong values every library is known to produce,
+// per GOARCH, with the reason. Cells are named "<lib>/<target>/<case>", as
+// the rows of Benchmark_Integers. A wrong value that is not listed fails
+// the test, as does a listed one that is right now.
+var intKnown = map[string]map[string]string{
+	"amd64": {
+		"Jackpal/int/max64+1":   jackpalWrap,
+		"Jackpal/int/maxu64":    jackpalWrap,
+		"Jackpal/int/2^100":     jackpalFloat,
+		"Jackpal/int64/max64+1": jackpalWrap,
+		"Jackpal/int64/maxu64":  jackpalWrap,
+		"Jackpal/int64/2^100":   jackpalFloat,
+		"Jackpal/uint64/min64":  jackpalWrap,
+		"Jackpal/uint64/2^100":  jackpalFloat,
+		"Lwch/uint64/min64":     "the int64 is converted to uint64 without a sign check",
+	},
+	"386": {
+		"Zeebo/int/2^32+1":      "SetInt stores the int64 without an OverflowInt check",
+		"Zeebo/int/max64":       "SetInt stores the int64 without an OverflowInt check",
+		"Zeebo/int/min64":       "SetInt stores the int64 without an OverflowInt check",
+		"Jackpal/int/2^32+1":    jackpalWrap,
+		"Jackpal/int/max64":     jackpalWrap,
+		"Jackpal/int/min64":     jackpalWrap,
+		"Jackpal/int/max64+1":   jackpalWrap,
+		"Jackpal/int/maxu64":    jackpalWrap,
+		"Jackpal/int/2^100":     jackpalFloat,
+		"Jackpal/int64/max64+1": jackpalWrap,
+		"Jackpal/int64/maxu64":  jackpalWrap,
+		"Jackpal/int64/2^100":   jackpalFloat,
+		"Jackpal/uint64/min64":  jackpalWrap,
+		"Jackpal/uint64/2^100":  jackpalFloat,
+		"Lwch/generic/2^32+1":   "the generic integer is an int, SetInt stores the int64 without an OverflowInt check",
+		"Lwch/generic/max64":    "the generic integer is an int, SetInt stores the int64 without an OverflowInt check",
+		"Lwch/generic/min64":    "the generic integer is an int, SetInt stores the int64 without an OverflowInt check",
+		"Lwch/int/2^32+1":       "SetInt stores the int64 without an OverflowInt check",
+		"Lwch/int/max64":        "SetInt stores the int64 without an OverflowInt check",
+		"Lwch/int/min64":        "SetInt stores the int64 without an OverflowInt check",
+		"Lwch/uint64/min64":     "the int64 is converted to uint64 without a sign check",
+	},
+}
+
+// The reasons of Jackpal, which parses as int64, falls back to uint64 and
+// float64 and converts the result to the target without a range check.
+const (
+	jackpalWrap  = "the parsed value is converted to the target without a range check"
+	jackpalFloat = "the value is parsed as a float64, its conversion to the target is out of range"
+)
+
+// intDoc wraps the integer into a dictionary, as it is in a metainfo file.
+func intDoc(value string) []byte {
+	return []byte("d6:lengthi" + value + "ee")
+}
+
+// TestIntegerRange decodes every integer case into every target and prints
+// a matrix per library. "ok" is an exact value, "str" the exact value as a
+// string, "err" is a rejection, "shape" a result that holds no integer at
+// all and anything else is the value the library silently produced
+// instead, which fails the test unless it is listed in intKnown.
+// Run it natively and as a 32-bit build to catch truncation to int:
+//
+//	go test -run TestIntegerRange -v
+//	GOARCH=386 go test -run TestIntegerRange -v
+func TestIntegerRange(t *testing.T) {
+	header := []string{runtime.GOARCH, "target"}
+	for _, c := range intCases {
+		header = append(header, c.name)
+	}
+	table := [][]string{header}
+
+	for _, lib := range bencodeLibs {
+		for _, target := range intTargets {
+			if (target.typ == nil && lib.decode == nil) || (target.typ != nil && lib.unmarshal == nil) {
+				continue
+			}
+
+			row := []string{lib.name, target.name}
+			for _, c := range intCases {
+				key := lib.name + "/" + target.name + "/" + c.name
+				reason, known := intKnown[runtime.GOARCH][key]
+				got, err := decodeInt(lib, target.typ, intDoc(c.value))
+				wrong := false
+				switch {
+				case errors.Is(err, errIntShape):
+					got = "shape"
+				case err != nil:
+					got = "err"
+				case got == c.value:
+					got = "ok"
+				case got == fmt.Sprintf("%q", c.value):
+					got = "str"
+				default:
+					wrong = true
+				}
+				switch {
+				case wrong && known:
+					t.Logf("%s: known, got %s: %s", key, got, reason)
+				case wrong:
+					t.Errorf("%s: got %s", key, got)
+				case known:
+					t.Errorf("%s: %s now, drop it from intKnown", key, got)
+				}
+				row = append(row, got)
+			}
+			table = append(table, row)
+		}
+	}
+
+	for _, line := range alignColumns(table) {
+		t.Log(line)
+	}
+}
+
+// alignColumns pads every cell to the widest one in its column.
+func alignColumns(table [][]string) []string {
+	var widths []int
+	for _, row := range table {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+
+	lines := make([]string, len(table))
+	for i, row := range table {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			cells[j] = fmt.Sprintf("%-*s", widths[j], cell)
+		}
+		lines[i] = strings.TrimRight(strings.Join(cells, " "), " ")
+	}
+	return lines
+}
+
+// Benchmark_Integers decodes every integer case into every target
+// the library handles without an error.
+func Benchmark_Integers(b *testing.B) {
+	for _, lib := range bencodeLibs {
+		lib := lib
+		for _, target := range intTargets {
+			if (target.typ == nil && lib.decode == nil) || (target.typ != nil && lib.unmarshal == nil) {
+				continue
+			}
+			typ := target.typ
+			var dst reflect.Type
+			if typ != nil {
+				dst = intStruct(typ)
+			}
+			for _, c := range intCases {
+				data := intDoc(c.value)
+				if _, err := decodeInt(lib, typ, data); err != nil {
+					continue
+				}
+
+				b.Run(lib.name+"/"+target.name+"/"+c.name, func(b *testing.B) {
+					b.ReportAllocs()
+					for n := 0; n < b.N; n++ {
+						var err error
+						if typ == nil {
+							_, err = lib.decode(data)
+						} else {
+							err = lib.unmarshal(data, reflect.New(dst).Interface())
+						}
+						if err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// decodeInt decodes the "length" value from data into typ,
+// or into the generic representation when typ is nil.
+func decodeInt(lib bencodeLib, typ reflect.Type, data []byte) (string, error) {
+	if typ == nil {
+		res, err := safeDecode(lib, data)
+		if err != nil {
+			return "", err
+		}
+		// Some libraries decode into a named map type, like Chihaya's Dict.
+		dict := reflect.ValueOf(res)
+		if dict.Kind() != reflect.Map || dict.Type().Key().Kind() != reflect.String {
+			return "", fmt.Errorf("%w: %T", errIntShape, res)
+		}
+		length := dict.MapIndex(reflect.ValueOf("length").Convert(dict.Type().Key()))
+		if !length.IsValid() {
+			return "", fmt.Errorf("%w: no length in %T", errIntShape, res)
+		}
+		return intString(length.Interface())
+	}
+
+	v := reflect.New(intStruct(typ))
+	if err := safeUnmarshal(lib, data, v.Interface()); err != nil {
+		return "", err
+	}
+	return intString(v.Elem().Field(0).Interface())
+}
+
+// intString formats a decoded integer, whatever type the library picked.
+func intString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), nil
+	case float64:
+		return new(big.Float).SetFloat64(v).Text('f', 0), nil
+	case *big.Int:
+		if v == nil {
+			return "nil", nil
+		}
+		return v.String(), nil
+	case big.Int:
+		return v.String(), nil
+	case []byte:
+		return fmt.Sprintf("%q", v), nil
+	case string:
+		return fmt.Sprintf("%q", v), nil
+	default:
+		return "", fmt.Errorf("%w: %T", errIntShape, v)
+	}
+}
+
+// intStruct is a struct with the "length" field of type typ.
+func intStruct(typ reflect.Type) reflect.Type {
+	return reflect.StructOf([]reflect.StructField{
+		{Name: "Length", Type: typ, Tag: `bencode:"length"`},
+	})
+}
@@ -0,0 +1,46 @@
+//go:build goexperiment.jsonv2
+// +build goexperiment.jsonv2
+
+package bencode
+
+import (
+	jsonv2 "encoding/json/v2"
+	"testing"
+)
+
+func Benchmark_JSONv2_Marshal(b *testing.B) {
+	data, err := jsonv2.Marshal(torrentBenchData)
+	if err != nil {
+		b.Fatal(err)
+	}
+	reportDocSize(b, data)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		_, err := jsonv2.Marshal(torrentBenchData)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_JSONv2_UnmarshalFresh(b *testing.B) {
+	data, err := jsonv2.Marshal(torrentBenchData)
+	if err != nil {
+		b.Fatal(err)
+	}
+	reportDocSize(b, data)
+	benchDecodeInto(b, true, data, newTorrentTarget, func(data []byte, dst interface{}) error {
+		return jsonv2.Unmarshal(data, dst)
+	})
+}
+
+func Benchmark_JSONv2_UnmarshalReused(b *testing.B) {
+	data, err := jsonv2.Marshal(torrentBenchData)
+	if err != nil {
+		b.Fatal(err)
+	}
+	reportDocSize(b, data)
+	benchDecodeInto(b, false, data, newTorrentTarget, func(data []byte, dst interface{}) error {
+		return jsonv2.Unmarshal(data, dst)
+	})
+}
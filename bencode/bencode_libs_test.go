@@ -0,0 +1,279 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	bencode2 "github.com/IncSW/go-bencode"
+	bencode8 "github.com/anacrolix/torrent/bencode"
+	bencode7 "github.com/chihaya/chihaya/frontend/http/bencode"
+	bencode1 "github.com/cristalhq/bencode"
+	bencode16 "github.com/cuberat/go-bencode"
+	bencode11 "github.com/ehmry/go-bencode"
+	bencode6 "github.com/jackpal/bencode-go"
+	bencode15 "github.com/lajide/bencode"
+	bencode13 "github.com/lwch/bencode"
+	bencode5 "github.com/marksamman/bencode"
+	bencode4 "github.com/nabilanam/bencode/decoder"
+	bencode4e "github.com/nabilanam/bencode/encoder"
+	bencode9 "github.com/owenliang/dht"
+	bencode12 "github.com/stints/bencode"
+	bencode10 "github.com/tumdum/bencoding"
+	bencode3 "github.com/zeebo/bencode"
+)
+
+// bencodeLib drives a library from the checks that run the same
+// scenario against every decoder, as opposed to the benchmarks
+// that call each library by hand.
+type bencodeLib struct {
+	name string
+
+	// decode decodes data into the library's generic representation.
+	decode func(data []byte) (interface{}, error)
+
+	// unmarshal decodes data into the value pointed to by v.
+	unmarshal func(data []byte, v interface{}) error
+
+	// marshal encodes v.
+	marshal func(v interface{}) ([]byte, error)
+}
+
+// errUnsupported is returned for an operation the library does not have.
+var errUnsupported = errors.New("not supported")
+
+// bencodeLibs lists every library from the benchmark files.
+// A nil function means the library has no such operation.
+var bencodeLibs = []bencodeLib{
+	{
+		name: "cristalhq",
+		decode: func(data []byte) (interface{}, error) {
+			var res interface{}
+			err := bencode1.NewDecodeBytes(data).Decode(&res)
+			return res, err
+		},
+		unmarshal: func(data []byte, v interface{}) error {
+			return bencode1.NewDecodeBytes(data).Decode(v)
+		},
+		marshal: func(v interface{}) ([]byte, error) {
+			return bencode1.Marshal(v)
+		},
+	},
+	{
+		name: "IncSW",
+		decode: func(data []byte) (interface{}, error) {
+			return bencode2.Unmarshal(data)
+		},
+		marshal: func(v interface{}) ([]byte, error) {
+			return bencode2.Marshal(v)
+		},
+	},
+	{
+		name: "Zeebo",
+		decode: func(data []byte) (interface{}, error) {
+			var res interface{}
+			err := bencode3.DecodeBytes(data, &res)
+			return res, err
+		},
+		unmarshal: func(data []byte, v interface{}) error {
+			return bencode3.DecodeBytes(data, v)
+		},
+		marshal: func(v interface{}) ([]byte, error) {
+			return bencode3.EncodeBytes(v)
+		},
+	},
+	{
+		name: "Nabilanam",
+		decode: func(data []byte) (interface{}, error) {
+			return bencode4.New(data).Decode(), nil
+		},
+		marshal: func(v interface{}) ([]byte, error) {
+			return []byte(bencode4e.New(v).Encode()), nil
+		},
+	},
+	{
+		name: "Marksamman",
+		decode: func(data []byte) (interface{}, error) {
+			return bencode5.Decode(bytes.NewReader(data))
+		},
+		marshal: func(v interface{}) ([]byte, error) {
+			dict, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, errUnsupported
+			}
+			return []byte(bencode5.Encode(dict)), nil
+		},
+	},
+	{
+		name: "Jackpal",
+		decode: func(data []byte) (interface{}, error) {
+			res, err := bencode6.Decode(bytes.NewReader(data))
+			if err == io.EOF {
+				err = nil
+			}
+			return res, err
+		},
+		unmarshal: func(data []byte, v interface{}) error {
+			return bencode6.Unmarshal(bytes.NewReader(data), v)
+		},
+		marshal: func(v interface{}) ([]byte, error) {
+			var buf bytes.Buffer
+			err := bencode6.Marshal(&buf, v)
+			return buf.Bytes(), err
+		},
+	},
+	{
+		name: "Chihaya",
+		decode: func(data []byte) (interface{}, error) {
+			return bencode7.Unmarshal(data)
+		},
+		marshal: func(v interface{}) ([]byte, error) {
+			var buf bytes.Buffer
+			err := bencode7.NewEncoder(&buf).Encode(v)
+			return buf.Bytes(), err
+		},
+	},
+	{
+		name: "Anacrolix",
+		decode: func(data []byte) (interface{}, error) {
+			var res interface{}
+			err := bencode8.Unmarshal(data, &res)
+			return res, err
+		},
+		unmarshal: func(data []byte, v interface{}) error {
+			return bencode8.Unmarshal(data, v)
+		},
+		marshal: func(v interface{}) ([]byte, error) {
+			return bencode8.Marshal(v)
+		},
+	},
+	{
+		name: "Owenliang",
+		decode: func(data []byte) (interface{}, error) {
+			return bencode9.Decode(data)
+		},
+		marshal: func(v interface{}) ([]byte, error) {
+			res, err := bencode9.Encode(v)
+			return []byte(res), err
+		},
+	},
+	{
+		name: "Tumdum",
+		decode: func(data []byte) (interface{}, error) {
+			var res interface{}
+			err := bencode10.Unmarshal(data, &res)
+			return res, err
+		},
+		unmarshal: func(data []byte, v interface{}) error {
+			return bencode10.Unmarshal(data, v)
+		},
+		marshal: func(v interface{}) ([]byte, error) {
+			var buf bytes.Buffer
+			err := bencode10.NewEncoder(&buf).Encode(v)
+			return buf.Bytes(), err
+		},
+	},
+	{
+		name: "Ehmry",
+		decode: func(data []byte) (interface{}, error) {
+			var res interface{}
+			err := bencode11.Unmarshal(data, &res)
+			return res, err
+		},
+		unmarshal: func(data []byte, v interface{}) error {
+			return bencode11.Unmarshal(data, v)
+		},
+		marshal: func(v interface{}) ([]byte, error) {
+			var buf bytes.Buffer
+			err := bencode11.NewEncoder(&buf).Encode(v)
+			return buf.Bytes(), err
+		},
+	},
+	{
+		name: "Stints",
+		marshal: func(v interface{}) ([]byte, error) {
+			return []byte(bencode12.NewEncoder().Encode(v)), nil
+		},
+	},
+	{
+		name: "Lwch",
+		decode: func(data []byte) (interface{}, error) {
+			var res interface{}
+			err := bencode13.Decode(data, &res)
+			return res, err
+		},
+		unmarshal: func(data []byte, v interface{}) error {
+			return bencode13.Decode(data, v)
+		},
+		marshal: func(v interface{}) ([]byte, error) {
+			var buf bytes.Buffer
+			err := bencode13.NewEncoder(&buf).Encode(v)
+			return buf.Bytes(), err
+		},
+	},
+	{
+		name: "Lajide",
+		decode: func(data []byte) (interface{}, error) {
+			res, err := bencode15.NewDecoder(bytes.NewBuffer(data)).Decode()
+			if err == io.EOF {
+				err = nil
+			}
+			return res, err
+		},
+		marshal: func(v interface{}) ([]byte, error) {
+			var buf bytes.Buffer
+			err := bencode15.NewEncoder(&buf).Encode(v)
+			return buf.Bytes(), err
+		},
+	},
+	{
+		name: "Cuberat",
+		decode: func(data []byte) (interface{}, error) {
+			res, err := bencode16.NewDecoder(bytes.NewBuffer(data)).Decode()
+			if err == io.EOF {
+				err = nil
+			}
+			return res, err
+		},
+		marshal: func(v interface{}) ([]byte, error) {
+			var buf bytes.Buffer
+			err := bencode16.NewEncoder(&buf).Encode(v)
+			return buf.Bytes(), err
+		},
+	},
+}
+
+// safeDecode calls lib.decode and turns a panic into an error,
+// some of the libraries panic on input they do not understand.
+func safeDecode(lib bencodeLib, data []byte) (res interface{}, err error) {
+	if lib.decode == nil {
+		return nil, errUnsupported
+	}
+	defer recoverError(&err)
+	return lib.decode(data)
+}
+
+// safeUnmarshal is safeDecode for lib.unmarshal.
+func safeUnmarshal(lib bencodeLib, data []byte, v interface{}) (err error) {
+	if lib.unmarshal == nil {
+		return errUnsupported
+	}
+	defer recoverError(&err)
+	return lib.unmarshal(data, v)
+}
+
+// safeMarshal is safeDecode for lib.marshal.
+func safeMarshal(lib bencodeLib, v interface{}) (data []byte, err error) {
+	if lib.marshal == nil {
+		return nil, errUnsupported
+	}
+	defer recoverError(&err)
+	return lib.marshal(v)
+}
+
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("panic: %v", r)
+	}
+}
@@ -23,36 +23,23 @@ import (
 
 var unmarshalBenchData = []byte("d4:infod6:lengthi170917888e12:piece lengthi262144e4:name30:debian-8.8.0-arm64-netinst.isoe8:announce38:udp://tracker.publicbt.com:80/announce13:announce-listll38:udp://tracker.publicbt.com:80/announceel44:udp://tracker.openbittorrent.com:80/announceee7:comment33:Debian CD from cdimage.debian.orge")
 
-func Benchmark_cristalhq_Unmarshal(b *testing.B) {
-	var res interface{}
-	b.ReportAllocs()
-	for n := 0; n < b.N; n++ {
-		err := bencode1.NewDecodeBytes(unmarshalBenchData).Decode(&res)
-		if err != nil {
-			b.Fatal(err)
-		}
-		if res == nil {
-			b.Fatal("is nil")
-		}
-	}
+func Benchmark_cristalhq_UnmarshalFresh(b *testing.B) {
+	benchDecodeInto(b, true, unmarshalBenchData, newInterfaceTarget, decodeCristalhqInto)
 }
 
-func Benchmark_cristalhq_UnmarshalReader(b *testing.B) {
-	var res interface{}
-	b.ReportAllocs()
-	for n := 0; n < b.N; n++ {
-		r := bytes.NewReader(unmarshalBenchData)
-		err := bencode1.NewDecoder(r).Decode(&res)
-		if err != nil {
-			b.Fatal(err)
-		}
-		if res == nil {
-			b.Fatal("is nil")
-		}
-	}
+func Benchmark_cristalhq_UnmarshalReused(b *testing.B) {
+	benchDecodeInto(b, false, unmarshalBenchData, newInterfaceTarget, decodeCristalhqInto)
 }
 
-func Benchmark_IncSW_Unmarshal(b *testing.B) {
+func Benchmark_cristalhq_UnmarshalReaderFresh(b *testing.B) {
+	benchDecodeInto(b, true, unmarshalBenchData, newInterfaceTarget, decodeCristalhqReaderInto)
+}
+
+func Benchmark_cristalhq_UnmarshalReaderReused(b *testing.B) {
+	benchDecodeInto(b, false, unmarshalBenchData, newInterfaceTarget, decodeCristalhqReaderInto)
+}
+
+func Benchmark_IncSW_UnmarshalFresh(b *testing.B) {
 	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		_, err := bencode2.Unmarshal(unmarshalBenchData)
@@ -62,21 +49,15 @@ func Benchmark_IncSW_Unmarshal(b *testing.B) {
 	}
 }
 
-func Benchmark_ZeeboBencode_Unmarshal(b *testing.B) {
-	res := map[string]interface{}{}
-	b.ReportAllocs()
-	for n := 0; n < b.N; n++ {
-		err := bencode3.DecodeBytes(unmarshalBenchData, &res)
-		if err != nil {
-			b.Fatal(err)
-		}
-		if res == nil {
-			b.Fatal("is nil")
-		}
-	}
+func Benchmark_ZeeboBencode_UnmarshalFresh(b *testing.B) {
+	benchDecodeInto(b, true, unmarshalBenchData, newMapTarget, bencode3.DecodeBytes)
 }
 
-func Benchmark_NabilanamBencode_Unmarshal(b *testing.B) {
+func Benchmark_ZeeboBencode_UnmarshalReused(b *testing.B) {
+	benchDecodeInto(b, false, unmarshalBenchData, newMapTarget, bencode3.DecodeBytes)
+}
+
+func Benchmark_NabilanamBencode_UnmarshalFresh(b *testing.B) {
 	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		res := bencode4.New(unmarshalBenchData).Decode()
@@ -86,7 +67,7 @@ func Benchmark_NabilanamBencode_Unmarshal(b *testing.B) {
 	}
 }
 
-func Benchmark_MarksammanBencode_Unmarshal(b *testing.B) {
+func Benchmark_MarksammanBencode_UnmarshalFresh(b *testing.B) {
 	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		r := bytes.NewReader(unmarshalBenchData)
@@ -97,7 +78,7 @@ func Benchmark_MarksammanBencode_Unmarshal(b *testing.B) {
 	}
 }
 
-func Benchmark_JackpalBencode_Unmarshal(b *testing.B) {
+func Benchmark_JackpalBencode_UnmarshalFresh(b *testing.B) {
 	b.Skip()
 	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
@@ -109,7 +90,7 @@ func Benchmark_JackpalBencode_Unmarshal(b *testing.B) {
 	}
 }
 
-func Benchmark_ChihayaBencode_Unmarshal(b *testing.B) {
+func Benchmark_ChihayaBencode_UnmarshalFresh(b *testing.B) {
 	b.Skip()
 	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
@@ -117,22 +98,15 @@ func Benchmark_ChihayaBencode_Unmarshal(b *testing.B) {
 	}
 }
 
-func Benchmark_AnacrolixTorrent_Unmarshal(b *testing.B) {
-	b.Skip()
-	res := map[string]interface{}{}
-	b.ReportAllocs()
-	for n := 0; n < b.N; n++ {
-		err := bencode8.Unmarshal(unmarshalBenchData, &res)
-		if err != nil {
-			b.Fatal(err)
-		}
-		if res == nil {
-			b.Fatal("is nil")
-		}
-	}
+func Benchmark_AnacrolixTorrent_UnmarshalFresh(b *testing.B) {
+	benchDecodeInto(b, true, unmarshalBenchData, newMapTarget, bencode8.Unmarshal)
+}
+
+func Benchmark_AnacrolixTorrent_UnmarshalReused(b *testing.B) {
+	benchDecodeInto(b, false, unmarshalBenchData, newMapTarget, bencode8.Unmarshal)
 }
 
-func Benchmark_OwenliangDht_Unmarshal(b *testing.B) {
+func Benchmark_OwenliangDht_UnmarshalFresh(b *testing.B) {
 	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		res, err := bencode9.Decode(unmarshalBenchData)
@@ -145,60 +119,53 @@ func Benchmark_OwenliangDht_Unmarshal(b *testing.B) {
 	}
 }
 
-func Benchmark_TumdumBencoding_Unmarshal(b *testing.B) {
+func Benchmark_TumdumBencoding_UnmarshalFresh(b *testing.B) {
 	b.Skip()
-	res := map[string]interface{}{}
-	b.ReportAllocs()
-	for n := 0; n < b.N; n++ {
-		err := bencode10.Unmarshal(unmarshalBenchData, &res)
-		if err != nil {
-			b.Fatal(err)
-		}
-		if res == nil {
-			b.Fatal("is nil")
-		}
-	}
+	benchDecodeInto(b, true, unmarshalBenchData, newMapTarget, func(data []byte, dst interface{}) error {
+		return bencode10.Unmarshal(data, dst)
+	})
 }
 
-func Benchmark_EhmryGoBencode_Unmarshal(b *testing.B) {
+func Benchmark_TumdumBencoding_UnmarshalReused(b *testing.B) {
 	b.Skip()
-	res := map[string]interface{}{}
-	b.ReportAllocs()
-	for n := 0; n < b.N; n++ {
-		err := bencode11.Unmarshal(unmarshalBenchData, &res)
-		if err != nil {
-			b.Fatal(err)
-		}
-		if res == nil {
-			b.Fatal("is nil")
-		}
-	}
+	benchDecodeInto(b, false, unmarshalBenchData, newMapTarget, func(data []byte, dst interface{}) error {
+		return bencode10.Unmarshal(data, dst)
+	})
 }
 
-func Benchmark_StintsBencode_Unmarshal(b *testing.B) {
+func Benchmark_EhmryGoBencode_UnmarshalFresh(b *testing.B) {
 	b.Skip()
+	benchDecodeInto(b, true, unmarshalBenchData, newMapTarget, func(data []byte, dst interface{}) error {
+		return bencode11.Unmarshal(data, dst)
+	})
 }
 
-func Benchmark_LwchBencode_Unmarshal(b *testing.B) {
+func Benchmark_EhmryGoBencode_UnmarshalReused(b *testing.B) {
 	b.Skip()
-	res := map[string]interface{}{}
-	b.ReportAllocs()
-	for n := 0; n < b.N; n++ {
-		err := bencode13.Decode(unmarshalBenchData, &res)
-		if err != nil {
-			b.Fatal(err)
-		}
-		if res == nil {
-			b.Fatal("is nil")
-		}
-	}
+	benchDecodeInto(b, false, unmarshalBenchData, newMapTarget, func(data []byte, dst interface{}) error {
+		return bencode11.Unmarshal(data, dst)
+	})
 }
 
-func Benchmark_ClearcodecnBencode_Unmarshal(b *testing.B) {
+func Benchmark_StintsBencode_UnmarshalFresh(b *testing.B) {
 	b.Skip()
 }
 
-func Benchmark_LajideBencode_Unmarshal(b *testing.B) {
+func Benchmark_LwchBencode_UnmarshalFresh(b *testing.B) {
+	b.Skip("not supported list in list")
+	benchDecodeInto(b, true, unmarshalBenchData, newMapTarget, bencode13.Decode)
+}
+
+func Benchmark_LwchBencode_UnmarshalReused(b *testing.B) {
+	b.Skip("not supported list in list")
+	benchDecodeInto(b, false, unmarshalBenchData, newMapTarget, bencode13.Decode)
+}
+
+func Benchmark_ClearcodecnBencode_UnmarshalFresh(b *testing.B) {
+	b.Skip()
+}
+
+func Benchmark_LajideBencode_UnmarshalFresh(b *testing.B) {
 	b.Skip()
 	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
@@ -213,7 +180,7 @@ func Benchmark_LajideBencode_Unmarshal(b *testing.B) {
 	}
 }
 
-func Benchmark_CuberatGoBencode_Unmarshal(b *testing.B) {
+func Benchmark_CuberatGoBencode_UnmarshalFresh(b *testing.B) {
 	b.Skip()
 	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
@@ -227,3 +194,50 @@ func Benchmark_CuberatGoBencode_Unmarshal(b *testing.B) {
 		}
 	}
 }
+
+// benchDecodeInto decodes data b.N times. With fresh set every iteration
+// gets a new target from newTarget, otherwise one target is kept for the
+// whole run. A target that is still nil after the decode fails.
+func benchDecodeInto(b *testing.B, fresh bool, data []byte, newTarget func() interface{}, decode func(data []byte, dst interface{}) error) {
+	b.ReportAllocs()
+	dst := newTarget()
+	for n := 0; n < b.N; n++ {
+		if fresh {
+			dst = newTarget()
+		}
+		err := decode(data, dst)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if isNilTarget(dst) {
+			b.Fatal("is nil")
+		}
+	}
+}
+
+// isNilTarget reports whether the value dst points to is nil.
+func isNilTarget(dst interface{}) bool {
+	switch dst := dst.(type) {
+	case *interface{}:
+		return *dst == nil
+	case *map[string]interface{}:
+		return *dst == nil
+	}
+	return false
+}
+
+func newInterfaceTarget() interface{} {
+	return new(interface{})
+}
+
+func newMapTarget() interface{} {
+	return &map[string]interface{}{}
+}
+
+func decodeCristalhqInto(data []byte, dst interface{}) error {
+	return bencode1.NewDecodeBytes(data).Decode(dst)
+}
+
+func decodeCristalhqReaderInto(data []byte, dst interface{}) error {
+	return bencode1.NewDecoder(bytes.NewReader(data)).Decode(dst)
+}
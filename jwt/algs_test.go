@@ -0,0 +1,215 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"fmt"
+	"strings"
+	"testing"
+
+	jwt1 "github.com/pascaldekloe/jwt"
+
+	jwt2 "github.com/cristalhq/jwt/v3"
+)
+
+// oneAlg signs and checks with one jwt1 algorithm and key.
+type oneAlg struct {
+	name  string
+	sign  func(c *jwt1.Claims) ([]byte, error)
+	check func(token []byte) (*jwt1.Claims, error)
+}
+
+// twoAlg is a jwt2 signer and verifier for one algorithm and key.
+type twoAlg struct {
+	name     string
+	signer   jwt2.Signer
+	verifier jwt2.Verifier
+}
+
+// oneAlgs covers every algorithm family once, RSA with a 2048-bit key.
+func oneAlgs(tb testing.TB) []oneAlg {
+	return append(oneNonRSAAlgs(), oneRSAAlg(jwt1.RS384, jwt1.RS384, testKeyRSA(2048)))
+}
+
+// oneAllAlgs covers every algorithm, RSA with the key grids of
+// Benchmark_One_RSA and Benchmark_One_RSAPSS.
+func oneAllAlgs(tb testing.TB) []oneAlg {
+	tb.Helper()
+	algs := oneNonRSAAlgs()
+	algs = append(algs, oneRSAGrid(tb, []string{jwt1.RS256, jwt1.RS384, jwt1.RS512}, testKeysRSA(rsaKeySizes))...)
+	return append(algs, oneRSAGrid(tb, []string{jwt1.PS256, jwt1.PS384, jwt1.PS512}, testKeysRSA(rsaPSSKeySizes))...)
+}
+
+// oneNonRSAAlgs are ES256, ES384, ES512, EdDSA, HS256, HS384 and HS512.
+func oneNonRSAAlgs() []oneAlg {
+	var algs []oneAlg
+	for _, test := range []struct {
+		key *ecdsa.PrivateKey
+		alg string
+	}{
+		{testKeyEC(256), jwt1.ES256},
+		{testKeyEC(384), jwt1.ES384},
+		{testKeyEC(521), jwt1.ES512},
+	} {
+		test := test
+		algs = append(algs, oneAlg{
+			name: test.alg,
+			sign: func(c *jwt1.Claims) ([]byte, error) {
+				return c.ECDSASign(test.alg, test.key)
+			},
+			check: func(token []byte) (*jwt1.Claims, error) {
+				return jwt1.ECDSACheck(token, &test.key.PublicKey)
+			},
+		})
+	}
+	algs = append(algs, oneAlg{
+		name: jwt1.EdDSA,
+		sign: func(c *jwt1.Claims) ([]byte, error) {
+			return c.EdDSASign(testKeyEd25519())
+		},
+		check: func(token []byte) (*jwt1.Claims, error) {
+			return jwt1.EdDSACheck(token, testKeyEd25519Public())
+		},
+	})
+	for _, name := range []string{jwt1.HS256, jwt1.HS384, jwt1.HS512} {
+		name := name
+		algs = append(algs, oneAlg{
+			name: name,
+			sign: func(c *jwt1.Claims) ([]byte, error) {
+				return c.HMACSign(name, testSecret)
+			},
+			check: func(token []byte) (*jwt1.Claims, error) {
+				return jwt1.HMACCheck(token, testSecret)
+			},
+		})
+	}
+	return algs
+}
+
+// oneRSAGrid crosses algs with keys, named as the RSA benchmarks. Pairs
+// jwt1 cannot sign with are left out, PS512 does not fit a 1024-bit key.
+func oneRSAGrid(tb testing.TB, algs []string, keys []*rsa.PrivateKey) []oneAlg {
+	tb.Helper()
+	var grid []oneAlg
+	for _, alg := range algs {
+		for _, key := range keys {
+			name := fmt.Sprintf("%s-%d-bit", alg, key.Size()*8)
+			if _, err := (&jwt1.Claims{}).RSASign(alg, key); err != nil {
+				tb.Logf("%s: left out: %v", name, err)
+				continue
+			}
+			grid = append(grid, oneRSAAlg(name, alg, key))
+		}
+	}
+	return grid
+}
+
+// oneRSAAlg is alg with key, both RS and PS.
+func oneRSAAlg(name, alg string, key *rsa.PrivateKey) oneAlg {
+	return oneAlg{
+		name: name,
+		sign: func(c *jwt1.Claims) ([]byte, error) {
+			return c.RSASign(alg, key)
+		},
+		check: func(token []byte) (*jwt1.Claims, error) {
+			return jwt1.RSACheck(token, &key.PublicKey)
+		},
+	}
+}
+
+// twoAlgs is the jwt2 counterpart of oneAlgs.
+func twoAlgs(tb testing.TB) []twoAlg {
+	tb.Helper()
+	return append(twoNonRSAAlgs(tb), twoRSAAlg(tb, jwt2.RS384.String(), jwt2.RS384, testKeyRSA(2048)))
+}
+
+// twoAllAlgs is the jwt2 counterpart of oneAllAlgs.
+func twoAllAlgs(tb testing.TB) []twoAlg {
+	tb.Helper()
+	algs := twoNonRSAAlgs(tb)
+	algs = append(algs, twoRSAGrid(tb, []jwt2.Algorithm{jwt2.RS256, jwt2.RS384, jwt2.RS512}, testKeysRSA(rsaKeySizes))...)
+	return append(algs, twoRSAGrid(tb, []jwt2.Algorithm{jwt2.PS256, jwt2.PS384, jwt2.PS512}, testKeysRSA(rsaPSSKeySizes))...)
+}
+
+// twoNonRSAAlgs is the jwt2 counterpart of oneNonRSAAlgs.
+func twoNonRSAAlgs(tb testing.TB) []twoAlg {
+	tb.Helper()
+	var algs []twoAlg
+	add := func(name string, signer jwt2.Signer, verifier jwt2.Verifier, err error) {
+		tb.Helper()
+		if err != nil {
+			tb.Fatal(err)
+		}
+		algs = append(algs, twoAlg{name, signer, verifier})
+	}
+
+	for _, test := range []struct {
+		key *ecdsa.PrivateKey
+		alg jwt2.Algorithm
+	}{
+		{testKeyEC(256), jwt2.ES256},
+		{testKeyEC(384), jwt2.ES384},
+		{testKeyEC(521), jwt2.ES512},
+	} {
+		signer, err := jwt2.NewSignerES(test.alg, test.key)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		verifier, err := jwt2.NewVerifierES(test.alg, &test.key.PublicKey)
+		add(test.alg.String(), signer, verifier, err)
+	}
+
+	signer, err := jwt2.NewSignerEdDSA(testKeyEd25519())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	verifier, err := jwt2.NewVerifierEdDSA(testKeyEd25519Public())
+	add(jwt2.EdDSA.String(), signer, verifier, err)
+
+	for _, name := range []jwt2.Algorithm{jwt2.HS256, jwt2.HS384, jwt2.HS512} {
+		signer, err := jwt2.NewSignerHS(name, testSecret)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		verifier, err := jwt2.NewVerifierHS(name, testSecret)
+		add(name.String(), signer, verifier, err)
+	}
+	return algs
+}
+
+// twoRSAGrid is the jwt2 counterpart of oneRSAGrid. jwt2 shortens the PS
+// salt to fit, but the pairs jwt1 leaves out are left out here as well,
+// so both grids have the same rows.
+func twoRSAGrid(tb testing.TB, algs []jwt2.Algorithm, keys []*rsa.PrivateKey) []twoAlg {
+	tb.Helper()
+	var grid []twoAlg
+	for _, alg := range algs {
+		for _, key := range keys {
+			name := fmt.Sprintf("%s-%d-bit", alg, key.Size()*8)
+			if err := pssFits(alg.String(), key); err != nil {
+				tb.Logf("%s: left out: %v", name, err)
+				continue
+			}
+			grid = append(grid, twoRSAAlg(tb, name, alg, key))
+		}
+	}
+	return grid
+}
+
+// twoRSAAlg is alg with key, both RS and PS.
+func twoRSAAlg(tb testing.TB, name string, alg jwt2.Algorithm, key *rsa.PrivateKey) twoAlg {
+	tb.Helper()
+	newSigner, newVerifier := jwt2.NewSignerRS, jwt2.NewVerifierRS
+	if strings.HasPrefix(alg.String(), "PS") {
+		newSigner, newVerifier = jwt2.NewSignerPS, jwt2.NewVerifierPS
+	}
+	signer, err := newSigner(alg, key)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	verifier, err := newVerifier(alg, &key.PublicKey)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return twoAlg{name, signer, verifier}
+}
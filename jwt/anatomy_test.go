@@ -0,0 +1,109 @@
+package jwt_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	jwt2 "github.com/cristalhq/jwt/v3"
+)
+
+// tokenSizes sums the encoded parts of the tokens made in a benchmark.
+type tokenSizes struct {
+	header, payload, sig, total int
+}
+
+func (s *tokenSizes) add(token []byte) {
+	dot1 := bytes.IndexByte(token, '.')
+	dot2 := bytes.LastIndexByte(token, '.')
+	s.header += dot1
+	s.payload += dot2 - dot1 - 1
+	s.sig += len(token) - dot2 - 1
+	s.total += len(token)
+}
+
+func (s *tokenSizes) report(b *testing.B) {
+	n := float64(b.N)
+	b.ReportMetric(float64(s.total)/n, "B/token")
+	b.ReportMetric(float64(s.header)/n, "B/header")
+	b.ReportMetric(float64(s.payload)/n, "B/payload")
+	b.ReportMetric(float64(s.sig)/n, "B/sig")
+}
+
+// TestTokenDiff compares the header, payload and signature of the benchmark
+// claims signed by both libraries, for every algorithm. The headers differ
+// in "typ" only, which Two adds, the payloads in the fraction of "iat" only,
+// which Two drops, and the signatures have the same length but not the same
+// bytes. Run with -v to see the decoded parts side by side, so a size
+// difference can be traced to a field:
+//
+//	go test -run TestTokenDiff -v
+func TestTokenDiff(t *testing.T) {
+	t.Logf("%-14s %-8s %-46s %s", "alg", "part", "one", "two")
+	for _, alg := range interopAlgs(t) {
+		one, err := alg.oneSign(benchClaims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		two, err := jwt2.NewBuilder(alg.twoSigner).BuildBytes(mybenchClaims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		oneParts := strings.Split(string(one), ".")
+		twoParts := strings.Split(string(two), ".")
+
+		var decoded [2][2]map[string]interface{}
+		for i, name := range []string{"header", "payload"} {
+			a := tokenDiffPart(t, oneParts[i])
+			b := tokenDiffPart(t, twoParts[i])
+			t.Logf("%-14s %-8s %-46s %s", alg.name, name, a, b)
+			t.Logf("%-14s %-8s %-46d %d", "", "", len(oneParts[i]), len(twoParts[i]))
+			for j, part := range [][]byte{a, b} {
+				if err := json.Unmarshal(part, &decoded[i][j]); err != nil {
+					t.Fatalf("%s: %s: %v", alg.name, name, err)
+				}
+			}
+		}
+		t.Logf("%-14s %-8s %-46d %d", "", "sig", len(oneParts[2]), len(twoParts[2]))
+
+		name := strings.SplitN(alg.name, "-", 2)[0]
+		oneHeader, twoHeader := decoded[0][0], decoded[0][1]
+		if want := map[string]interface{}{"alg": name}; !reflect.DeepEqual(oneHeader, want) {
+			t.Errorf("%s: one header %v, want %v", alg.name, oneHeader, want)
+		}
+		if want := map[string]interface{}{"alg": name, "typ": "JWT"}; !reflect.DeepEqual(twoHeader, want) {
+			t.Errorf("%s: two header %v, want %v", alg.name, twoHeader, want)
+		}
+
+		onePayload, twoPayload := decoded[1][0], decoded[1][1]
+		oneIat, _ := onePayload["iat"].(float64)
+		twoIat, _ := twoPayload["iat"].(float64)
+		if twoIat != math.Trunc(twoIat) || math.Abs(oneIat-twoIat) >= 1 {
+			t.Errorf("%s: iat %v and %v, want the same second with the fraction dropped by two", alg.name, onePayload["iat"], twoPayload["iat"])
+		}
+		delete(onePayload, "iat")
+		delete(twoPayload, "iat")
+		if !reflect.DeepEqual(onePayload, twoPayload) {
+			t.Errorf("%s: payloads %v and %v differ besides iat", alg.name, onePayload, twoPayload)
+		}
+
+		switch {
+		case len(oneParts[2]) != len(twoParts[2]):
+			t.Errorf("%s: signatures of %d and %d B", alg.name, len(oneParts[2]), len(twoParts[2]))
+		case oneParts[2] == twoParts[2]:
+			t.Errorf("%s: same signature over different headers", alg.name)
+		}
+	}
+}
+
+func tokenDiffPart(t *testing.T, part string) []byte {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,102 @@
+package jwt_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	jwt1 "github.com/pascaldekloe/jwt"
+
+	jwt2 "github.com/cristalhq/jwt/v3"
+)
+
+// checkCase is a signed token and the full path an API takes for it:
+// parse the compact form, verify the signature and decode the claims.
+type checkCase struct {
+	name  string
+	token []byte
+	check func(token []byte) error
+}
+
+func Benchmark_One_Check(b *testing.B) {
+	benchCheck(b, oneCheckCases(b))
+}
+
+func Benchmark_Two_Check(b *testing.B) {
+	benchCheck(b, twoCheckCases(b))
+}
+
+func benchCheck(b *testing.B, cases []checkCase) {
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				err := c.check(c.token)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// checkRSASizes are the RSA keys of the Check benchmarks.
+var checkRSASizes = []int{1024, 2048, 4096}
+
+// oneCheckCases uses the *Check functions, they parse, verify
+// and decode into jwt1.Claims in a single call.
+func oneCheckCases(b *testing.B) []checkCase {
+	algs := append(oneNonRSAAlgs(), oneRSAGrid(b, []string{jwt1.RS384}, testKeysRSA(checkRSASizes))...)
+	var cases []checkCase
+	for _, alg := range algs {
+		check := alg.check
+		token, err := alg.sign(benchClaims)
+		if err != nil {
+			b.Fatal(err)
+		}
+		cases = append(cases, checkCase{
+			name:  alg.name,
+			token: token,
+			check: func(token []byte) error {
+				claims, err := check(token)
+				if err == nil && claims.Issuer != "benchmark" {
+					err = fmt.Errorf("got issuer %q", claims.Issuer)
+				}
+				return err
+			},
+		})
+	}
+	return cases
+}
+
+// twoCheckCases uses ParseAndVerify, which also matches the header
+// algorithm, and then decodes the claims with encoding/json.
+func twoCheckCases(b *testing.B) []checkCase {
+	algs := append(twoNonRSAAlgs(b), twoRSAGrid(b, []jwt2.Algorithm{jwt2.RS384}, testKeysRSA(checkRSASizes))...)
+	var cases []checkCase
+	for _, alg := range algs {
+		verifier := alg.verifier
+		token, err := jwt2.NewBuilder(alg.signer).BuildBytes(mybenchClaims)
+		if err != nil {
+			b.Fatal(err)
+		}
+		cases = append(cases, checkCase{
+			name:  alg.name,
+			token: token,
+			check: func(token []byte) error {
+				tok, err := jwt2.ParseAndVerify(token, verifier)
+				if err != nil {
+					return err
+				}
+				var claims jwt2.StandardClaims
+				if err := json.Unmarshal(tok.RawClaims(), &claims); err != nil {
+					return err
+				}
+				if claims.Issuer != "benchmark" {
+					return fmt.Errorf("got issuer %q", claims.Issuer)
+				}
+				return nil
+			},
+		})
+	}
+	return cases
+}
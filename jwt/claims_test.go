@@ -0,0 +1,279 @@
+package jwt_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	jwt1 "github.com/pascaldekloe/jwt"
+
+	jwt2 "github.com/cristalhq/jwt/v3"
+)
+
+// appClaims are application claims, next to the registered ones.
+type appClaims struct {
+	Roles       []string        `json:"roles"`
+	TenantID    string          `json:"tenant_id"`
+	Permissions []permission    `json:"permissions"`
+	Profile     json.RawMessage `json:"profile"`
+}
+
+type permission struct {
+	Resource string           `json:"resource"`
+	Actions  []string         `json:"actions"`
+	Scope    *permissionScope `json:"scope,omitempty"`
+}
+
+type permissionScope struct {
+	Projects []string        `json:"projects"`
+	Limits   json.RawMessage `json:"limits,omitempty"`
+}
+
+// twoAppClaims is the claims struct of a jwt2 application.
+type twoAppClaims struct {
+	jwt2.StandardClaims
+	appClaims
+}
+
+// testAppClaims is the application part of the TestCustomClaims tokens.
+var testAppClaims = appClaims{
+	Roles:    []string{"admin", "billing"},
+	TenantID: "t-42",
+	Permissions: []permission{
+		{Resource: "invoices", Actions: []string{"read", "write"}},
+		{Resource: "projects", Actions: []string{"read"}, Scope: &permissionScope{
+			Projects: []string{"p-1", "p-7"},
+			Limits:   json.RawMessage(`{"rps":100,"burst":20}`),
+		}},
+	},
+	Profile: json.RawMessage(`{"name":"Ada","locale":"en-GB"}`),
+}
+
+// testAppIssued is a whole second, which both libraries keep as is.
+var testAppIssued = time.Unix(1600000000, 0)
+
+// unknownClaim is in the tokens of TestCustomClaims, but not in appClaims.
+const unknownClaim = "session"
+
+var unknownValue = map[string]interface{}{"id": "s-1", "device": "laptop"}
+
+// customPreserved is whether the unknown claim survives, keyed by library
+// and stage: "raw" after the check, "struct" after decoding into the
+// application struct, and "re-sign" after signing the decoded claims.
+var customPreserved = map[string]bool{
+	"one raw":     true,
+	"one struct":  false,
+	"one re-sign": true, // Set keeps every claim that is not registered
+	"two raw":     true,
+	"two struct":  false,
+	"two re-sign": false,
+}
+
+// oneAppClaims puts c in the Set map, which is how jwt1 signs
+// claims other than the registered ones.
+func oneAppClaims(c *appClaims) *jwt1.Claims {
+	return &jwt1.Claims{
+		Registered: jwt1.Registered{
+			Issuer: "benchmark",
+			Issued: jwt1.NewNumericTime(testAppIssued),
+		},
+		Set: map[string]interface{}{
+			"roles":       c.Roles,
+			"tenant_id":   c.TenantID,
+			"permissions": c.Permissions,
+			"profile":     c.Profile,
+		},
+	}
+}
+
+func newTwoAppClaims(c *appClaims) *twoAppClaims {
+	return &twoAppClaims{
+		StandardClaims: jwt2.StandardClaims{
+			Issuer:   "benchmark",
+			IssuedAt: jwt2.NewNumericDate(testAppIssued),
+		},
+		appClaims: *c,
+	}
+}
+
+// TestCustomClaims signs testAppClaims with an extra unknown claim,
+// decodes it into appClaims and reports where the unknown claim is kept.
+func TestCustomClaims(t *testing.T) {
+	hasUnknown := func(raw []byte) bool {
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &m); err != nil {
+			t.Fatal(err)
+		}
+		_, ok := m[unknownClaim]
+		return ok
+	}
+	got := map[string]bool{}
+
+	// jwt1
+	oneClaims := oneAppClaims(&testAppClaims)
+	oneClaims.Set[unknownClaim] = unknownValue
+	token, err := oneClaims.HMACSign(jwt1.HS256, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checked, err := jwt1.HMACCheck(token, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var oneApp appClaims
+	if err := json.Unmarshal(checked.Raw, &oneApp); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(oneApp, testAppClaims) {
+		t.Errorf("one: decoded %+v, want %+v", oneApp, testAppClaims)
+	}
+	if checked.Issuer != "benchmark" || !checked.Issued.Time().Equal(testAppIssued) {
+		t.Errorf("one: got iss %q and iat %s", checked.Issuer, checked.Issued.Time())
+	}
+	got["one raw"] = hasUnknown(checked.Raw)
+	got["one struct"] = hasUnknown(mustMarshalJSON(oneApp))
+	token, err = checked.HMACSign(jwt1.HS256, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resigned, err := jwt1.HMACCheck(token, testSecret); err != nil {
+		t.Fatal(err)
+	} else {
+		got["one re-sign"] = hasUnknown(resigned.Raw)
+	}
+
+	// jwt2
+	signer, err := jwt2.NewSignerHS(jwt2.HS256, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := jwt2.NewVerifierHS(jwt2.HS256, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withUnknown := struct {
+		*twoAppClaims
+		Session map[string]interface{} `json:"session"`
+	}{newTwoAppClaims(&testAppClaims), unknownValue}
+	token, err = jwt2.BuildBytes(signer, withUnknown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := jwt2.ParseAndVerify(token, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var twoApp twoAppClaims
+	if err := json.Unmarshal(tok.RawClaims(), &twoApp); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(twoApp.appClaims, testAppClaims) {
+		t.Errorf("two: decoded %+v, want %+v", twoApp.appClaims, testAppClaims)
+	}
+	if twoApp.Issuer != "benchmark" || !twoApp.IssuedAt.Equal(testAppIssued) {
+		t.Errorf("two: got iss %q and iat %s", twoApp.Issuer, twoApp.IssuedAt)
+	}
+	got["two raw"] = hasUnknown(tok.RawClaims())
+	got["two struct"] = hasUnknown(mustMarshalJSON(twoApp))
+	token, err = jwt2.BuildBytes(signer, &twoApp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resigned, err := jwt2.Parse(token); err != nil {
+		t.Fatal(err)
+	} else {
+		got["two re-sign"] = hasUnknown(resigned.RawClaims())
+	}
+
+	t.Logf("%-4s %-8s %-8s %-8s", "lib", "raw", "struct", "re-sign")
+	for _, lib := range []string{"one", "two"} {
+		t.Logf("%-4s %-8t %-8t %-8t", lib, got[lib+" raw"], got[lib+" struct"], got[lib+" re-sign"])
+	}
+	for key, want := range customPreserved {
+		if got[key] != want {
+			t.Errorf("%s: unknown claim preserved %t, customPreserved has %t", key, got[key], want)
+		}
+	}
+
+	// both libraries must produce the same application claims
+	if !bytes.Equal(mustMarshalJSON(oneApp), mustMarshalJSON(twoApp.appClaims)) {
+		t.Errorf("one and two decode differently:\n%s\n%s", mustMarshalJSON(oneApp), mustMarshalJSON(twoApp.appClaims))
+	}
+}
+
+// CustomClaims benchmarks sign testAppClaims, and check and decode
+// it into appClaims. HS256 keeps the crypto out of the way.
+
+func Benchmark_One_CustomClaims(b *testing.B) {
+	b.Run("sign", func(b *testing.B) {
+		var sizes tokenSizes
+		for i := 0; i < b.N; i++ {
+			token, err := oneAppClaims(&testAppClaims).HMACSign(jwt1.HS256, testSecret)
+			if err != nil {
+				b.Fatal(err)
+			}
+			sizes.add(token)
+		}
+		sizes.report(b)
+	})
+
+	token, err := oneAppClaims(&testAppClaims).HMACSign(jwt1.HS256, testSecret)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("check", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			claims, err := jwt1.HMACCheck(token, testSecret)
+			if err != nil {
+				b.Fatal(err)
+			}
+			var app appClaims
+			if err := json.Unmarshal(claims.Raw, &app); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func Benchmark_Two_CustomClaims(b *testing.B) {
+	signer, err := jwt2.NewSignerHS(jwt2.HS256, testSecret)
+	if err != nil {
+		b.Fatal(err)
+	}
+	verifier, err := jwt2.NewVerifierHS(jwt2.HS256, testSecret)
+	if err != nil {
+		b.Fatal(err)
+	}
+	bui := jwt2.NewBuilder(signer)
+
+	b.Run("sign", func(b *testing.B) {
+		var sizes tokenSizes
+		for i := 0; i < b.N; i++ {
+			token, err := bui.BuildBytes(newTwoAppClaims(&testAppClaims))
+			if err != nil {
+				b.Fatal(err)
+			}
+			sizes.add(token)
+		}
+		sizes.report(b)
+	})
+
+	token, err := bui.BuildBytes(newTwoAppClaims(&testAppClaims))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("check", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tok, err := jwt2.ParseAndVerify(token, verifier)
+			if err != nil {
+				b.Fatal(err)
+			}
+			var claims twoAppClaims
+			if err := json.Unmarshal(tok.RawClaims(), &claims); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
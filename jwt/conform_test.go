@@ -0,0 +1,283 @@
+package jwt_test
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	jwt1 "github.com/pascaldekloe/jwt"
+
+	jwt2 "github.com/cristalhq/jwt/v3"
+)
+
+// conformSecret signs the tokens of the conformance tests.
+var conformSecret = []byte("conformance test secret, 32 byte")
+
+// conformAudiences are the "aud" forms of RFC 7519, section 4.1.3.
+var conformAudiences = []struct {
+	name string
+	json string
+	want []string
+}{
+	{"string", `"api"`, []string{"api"}},
+	{"array-1", `["api"]`, []string{"api"}},
+	{"array-2", `["api","web"]`, []string{"api", "web"}},
+	{"empty-array", `[]`, []string{}},
+}
+
+// conformDates are NumericDate values of RFC 7519, section 2. The fractions
+// are exact in binary, so a float64 carries them without rounding.
+var conformDates = []struct {
+	name string
+	json string
+	want time.Time
+}{
+	{"whole", "1600000000", time.Unix(1600000000, 0)},
+	{"fraction", "1600000000.25", time.Unix(1600000000, 250000000)},
+	{"negative", "-1000000000", time.Unix(-1000000000, 0)},
+	{"negative-fraction", "-1.5", time.Unix(-2, 500000000)},
+	{"year-3000", "32503680000", time.Unix(32503680000, 0)},
+	{"year-3000-fraction", "32503680000.5", time.Unix(32503680000, 500000000)},
+}
+
+// conformDrift is how far a date may move and still read as ok. One keeps
+// NumericDate as float64 seconds, which resolve about 0.24µs at current
+// dates. Two writes whole seconds, a lost fraction is off by more.
+const conformDrift = time.Microsecond
+
+// conformKnown are the cells that differ from the input, with the reason.
+// Cells are named "<case> <trip>", a trip is "raw->one", "one->two" and so
+// on, where raw is a token with the JSON of the case as is.
+var conformKnown = map[string]string{
+	"empty-array one->one":        "one leaves an empty aud out",
+	"empty-array one->two":        "one leaves an empty aud out",
+	"empty-array two->one":        "two leaves an empty aud out",
+	"empty-array two->two":        "two leaves an empty aud out",
+	"fraction two->one":           "two writes whole seconds",
+	"fraction two->two":           "two writes whole seconds",
+	"negative-fraction two->one":  "two writes whole seconds, rounded down",
+	"negative-fraction two->two":  "two writes whole seconds, rounded down",
+	"year-3000-fraction raw->one": "one reads fractions as int64 nanoseconds, which overflow past 2262",
+	"year-3000-fraction one->one": "one writes fractions from int64 nanoseconds, which overflow past 2262",
+	"year-3000-fraction one->two": "one writes fractions from int64 nanoseconds, which overflow past 2262",
+	"year-3000-fraction two->one": "two writes whole seconds",
+	"year-3000-fraction two->two": "two writes whole seconds",
+}
+
+// conformLib signs and reads the registered claims with one library.
+// A zero date leaves iat and exp out, as does a nil aud.
+type conformLib struct {
+	name   string
+	sign   func(aud []string, date time.Time) ([]byte, error)
+	decode func(token []byte) (aud []string, iat, exp time.Time, err error)
+}
+
+func conformLibs(t *testing.T) []conformLib {
+	signer, err := jwt2.NewSignerHS(jwt2.HS256, conformSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := jwt2.NewVerifierHS(jwt2.HS256, conformSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []conformLib{{
+		name: "one",
+		sign: func(aud []string, date time.Time) ([]byte, error) {
+			c := &jwt1.Claims{Registered: jwt1.Registered{
+				Issuer:    "conform",
+				Audiences: aud,
+				Issued:    jwt1.NewNumericTime(date),
+				Expires:   jwt1.NewNumericTime(date),
+			}}
+			return c.HMACSign(jwt1.HS256, conformSecret)
+		},
+		decode: func(token []byte) ([]string, time.Time, time.Time, error) {
+			c, err := jwt1.HMACCheck(token, conformSecret)
+			if err != nil {
+				return nil, time.Time{}, time.Time{}, err
+			}
+			return c.Audiences, c.Issued.Time(), c.Expires.Time(), nil
+		},
+	}, {
+		name: "two",
+		sign: func(aud []string, date time.Time) ([]byte, error) {
+			return jwt2.BuildBytes(signer, &jwt2.StandardClaims{
+				Issuer:    "conform",
+				Audience:  aud,
+				IssuedAt:  jwt2.NewNumericDate(date),
+				ExpiresAt: jwt2.NewNumericDate(date),
+			})
+		},
+		decode: func(token []byte) ([]string, time.Time, time.Time, error) {
+			tok, err := jwt2.ParseAndVerify(token, verifier)
+			if err != nil {
+				return nil, time.Time{}, time.Time{}, err
+			}
+			var c jwt2.StandardClaims
+			if err := json.Unmarshal(tok.RawClaims(), &c); err != nil {
+				return nil, time.Time{}, time.Time{}, err
+			}
+			var iat, exp time.Time
+			if c.IssuedAt != nil {
+				iat = c.IssuedAt.Time
+			}
+			if c.ExpiresAt != nil {
+				exp = c.ExpiresAt.Time
+			}
+			return c.Audience, iat, exp, nil
+		},
+	}}
+}
+
+// TestAudienceConformance shows how each library encodes "aud" and
+// decodes every form, from a raw token and from the other library.
+func TestAudienceConformance(t *testing.T) {
+	libs := conformLibs(t)
+
+	t.Logf("%-12s %-16s %-16s %s", "aud", "one emits", "two emits", "decoded")
+	for _, c := range conformAudiences {
+		raw := []byte(forgeToken(`{"alg":"HS256"}`, `{"iss":"conform","aud":`+c.json+`}`, sha256.New, conformSecret))
+		tokens := map[string][]byte{"raw": raw}
+		emits := map[string]string{}
+		for _, lib := range libs {
+			token, err := lib.sign(c.want, time.Time{})
+			if err != nil {
+				t.Fatalf("%s: %s sign: %v", c.name, lib.name, err)
+			}
+			tokens[lib.name] = token
+			emits[lib.name] = conformClaim(t, token, "aud")
+		}
+
+		cells := map[string]string{}
+		for _, from := range []string{"raw", "one", "two"} {
+			for _, lib := range libs {
+				aud, _, _, err := lib.decode(tokens[from])
+				cells[from+"->"+lib.name] = conformAud(aud, err, c.want, emits[from] == "omitted")
+			}
+		}
+		t.Logf("%-12s %-16s %-16s %s", c.name, emits["one"], emits["two"], conformRow(cells))
+		conformVerdicts(t, c.name, cells)
+	}
+}
+
+// TestNumericDateConformance shows how each library encodes iat and exp
+// and decodes every value, from a raw token and from the other library.
+func TestNumericDateConformance(t *testing.T) {
+	libs := conformLibs(t)
+
+	t.Logf("%-18s %-20s %-16s %s", "date", "one emits", "two emits", "decoded")
+	for _, c := range conformDates {
+		claims := fmt.Sprintf(`{"iss":"conform","iat":%s,"exp":%s}`, c.json, c.json)
+		raw := []byte(forgeToken(`{"alg":"HS256"}`, claims, sha256.New, conformSecret))
+		tokens := map[string][]byte{"raw": raw}
+		emits := map[string]string{}
+		for _, lib := range libs {
+			token, err := lib.sign(nil, c.want)
+			if err != nil {
+				t.Fatalf("%s: %s sign: %v", c.name, lib.name, err)
+			}
+			tokens[lib.name] = token
+			emits[lib.name] = conformClaim(t, token, "iat")
+		}
+
+		cells := map[string]string{}
+		for _, from := range []string{"raw", "one", "two"} {
+			for _, lib := range libs {
+				_, iat, exp, err := lib.decode(tokens[from])
+				cell := conformDate(iat, err, c.want)
+				if other := conformDate(exp, err, c.want); other != cell {
+					cell += ", exp " + other
+				}
+				cells[from+"->"+lib.name] = cell
+			}
+		}
+		t.Logf("%-18s %-20s %-16s %s", c.name, emits["one"], emits["two"], conformRow(cells))
+		conformVerdicts(t, c.name, cells)
+	}
+}
+
+// conformTrips is the column order of the decoded cells.
+var conformTrips = []string{"raw->one", "raw->two", "one->one", "one->two", "two->one", "two->two"}
+
+func conformRow(cells map[string]string) string {
+	var row []string
+	for _, trip := range conformTrips {
+		row = append(row, trip+": "+cells[trip])
+	}
+	return strings.Join(row, " | ")
+}
+
+// conformVerdicts fails on cells that are not ok and not in conformKnown,
+// and on known ones that are ok now.
+func conformVerdicts(t *testing.T, name string, cells map[string]string) {
+	for _, trip := range conformTrips {
+		key := name + " " + trip
+		reason, known := conformKnown[key]
+		switch ok := strings.HasPrefix(cells[trip], "ok"); {
+		case ok && known:
+			t.Errorf("%s: works now, drop it from conformKnown", key)
+		case !ok && !known:
+			t.Errorf("%s: %s", key, cells[trip])
+		case !ok:
+			t.Logf("%s: known, %s", key, reason)
+		}
+	}
+}
+
+// conformClaim returns the JSON of a claim in token, or "omitted".
+func conformClaim(t *testing.T, token []byte, name string) string {
+	tok, err := jwt2.Parse(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(tok.RawClaims(), &m); err != nil {
+		t.Fatal(err)
+	}
+	v, ok := m[name]
+	if !ok {
+		return "omitted"
+	}
+	return string(v)
+}
+
+// conformAud is the cell of one decode. An aud the signer left out is
+// "omitted", even when the decoded value equals want.
+func conformAud(got []string, err error, want []string, omitted bool) string {
+	switch {
+	case err != nil:
+		return "rejected: " + err.Error()
+	case omitted:
+		return "omitted"
+	case len(got) == 0 && len(want) == 0, reflect.DeepEqual(got, want):
+		return "ok"
+	default:
+		return fmt.Sprintf("got %q", got)
+	}
+}
+
+func conformDate(got time.Time, err error, want time.Time) string {
+	switch {
+	case err != nil:
+		return "rejected: " + err.Error()
+	case got.IsZero():
+		return "missing"
+	}
+	drift := got.Sub(want)
+	switch {
+	case drift == 0:
+		return "ok"
+	case drift > -conformDrift && drift < conformDrift:
+		return fmt.Sprintf("ok, %s", drift)
+	case got.Year() == want.Year():
+		return fmt.Sprintf("off by %s", drift)
+	default:
+		return "got " + got.UTC().Format(time.RFC3339Nano)
+	}
+}
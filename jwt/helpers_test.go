@@ -0,0 +1,38 @@
+package jwt_test
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"hash"
+)
+
+// Helpers shared by the tests, none of them depends on a fixture.
+
+// testSecret is the HMAC secret of every test that does not sweep secrets.
+// Both libraries get the same one, so their tokens verify across.
+var testSecret = make([]byte, 64)
+
+// forgeToken builds a compact token from raw JSON. A nil digest gives
+// an empty signature, otherwise the token is signed with HMAC and key.
+func forgeToken(header, claims string, digest func() hash.Hash, key []byte) string {
+	body := encodeSegment(header) + "." + encodeSegment(claims)
+	if digest == nil {
+		return body + "."
+	}
+	mac := hmac.New(digest, key)
+	mac.Write([]byte(body))
+	return body + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func encodeSegment(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func mustMarshalJSON(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
@@ -0,0 +1,134 @@
+package jwt_test
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	jwt1 "github.com/pascaldekloe/jwt"
+
+	jwt2 "github.com/cristalhq/jwt/v3"
+)
+
+// hmacSecretSizes are the secret lengths in bytes of the HMAC sweep.
+var hmacSecretSizes = []int{16, 32, 48, 64, 128, 256}
+
+// hmacSecrets returns a secret for each of hmacSecretSizes.
+func hmacSecrets() [][]byte {
+	secrets := make([][]byte, len(hmacSecretSizes))
+	for i, n := range hmacSecretSizes {
+		secrets[i] = hmacSecret(n)
+	}
+	return secrets
+}
+
+// hmacSecret returns n random bytes. The source is seeded with n, so
+// every run benchmarks the same key.
+func hmacSecret(n int) []byte {
+	secret := make([]byte, n)
+	rand.New(rand.NewSource(int64(n))).Read(secret)
+	return secret
+}
+
+// hmacHashSizes is the output size in bytes per algorithm. RFC 7518,
+// section 3.2 requires a key of at least that size.
+var hmacHashSizes = map[string]int{
+	jwt1.HS256: 32,
+	jwt1.HS384: 48,
+	jwt1.HS512: 64,
+}
+
+// hmacMinSize is the smallest secret each entry point takes, keyed by
+// library and operation. RFC 7518, section 3.2 wants the hash output size,
+// neither library enforces it, only an empty secret is refused.
+var hmacMinSize = map[string]int{
+	"one sign":  1,
+	"one check": 1,
+	"one pool":  1,
+	"two sign":  1,
+	"two check": 1,
+}
+
+// hmacPolicySizes are the secret sizes of TestHMACKeyPolicy: empty, one
+// byte short of each hash output and the benchmark sweep.
+func hmacPolicySizes() []int {
+	sizes := []int{0, 31, 47, 63}
+	sizes = append(sizes, hmacSecretSizes...)
+	sort.Ints(sizes)
+	return sizes
+}
+
+// TestHMACKeyPolicy tries every size of hmacPolicySizes on every HMAC entry
+// point. A secret that is accepted while shorter than the hash output shows
+// as "weak". Any change from hmacMinSize fails, so a library upgrade that
+// starts enforcing key sizes shows up here.
+func TestHMACKeyPolicy(t *testing.T) {
+	sizes := hmacPolicySizes()
+	header := fmt.Sprintf("%-6s %-10s", "alg", "op")
+	for _, n := range sizes {
+		header += fmt.Sprintf(" %8s", fmt.Sprintf("%d B", n))
+	}
+	t.Log(header)
+
+	for _, alg := range []string{jwt1.HS256, jwt1.HS384, jwt1.HS512} {
+		ops := []struct {
+			name string
+			try  func(secret []byte) error
+		}{
+			{"one sign", func(secret []byte) error {
+				_, err := (&jwt1.Claims{}).HMACSign(alg, secret)
+				return err
+			}},
+			{"one check", func(secret []byte) error {
+				// a signature mismatch means the secret was taken
+				_, err := jwt1.HMACCheck(hmacPolicyToken(t, alg), secret)
+				if err == jwt1.ErrSigMiss {
+					return nil
+				}
+				return err
+			}},
+			{"one pool", func(secret []byte) error {
+				_, err := jwt1.NewHMAC(alg, secret)
+				return err
+			}},
+			{"two sign", func(secret []byte) error {
+				_, err := jwt2.NewSignerHS(jwt2.Algorithm(alg), secret)
+				return err
+			}},
+			{"two check", func(secret []byte) error {
+				_, err := jwt2.NewVerifierHS(jwt2.Algorithm(alg), secret)
+				return err
+			}},
+		}
+
+		for _, op := range ops {
+			row := fmt.Sprintf("%-6s %-10s", alg, op.name)
+			for _, n := range sizes {
+				accepted := op.try(hmacSecret(n)) == nil
+				cell := "rejected"
+				switch {
+				case accepted && n < hmacHashSizes[alg]:
+					cell = "weak"
+				case accepted:
+					cell = "ok"
+				}
+				row += fmt.Sprintf(" %8s", cell)
+
+				if want := n >= hmacMinSize[op.name]; accepted != want {
+					t.Errorf("%s %s: %d B secret accepted %t, hmacMinSize is %d B", alg, op.name, n, accepted, hmacMinSize[op.name])
+				}
+			}
+			t.Log(row)
+		}
+	}
+}
+
+// hmacPolicyToken is a valid token for alg, signed with a full size secret.
+func hmacPolicyToken(t *testing.T, alg string) []byte {
+	token, err := (&jwt1.Claims{}).HMACSign(alg, make([]byte, hmacHashSizes[alg]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
@@ -0,0 +1,273 @@
+package jwt_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	jwt1 "github.com/pascaldekloe/jwt"
+
+	jwt2 "github.com/cristalhq/jwt/v3"
+)
+
+// The claims every trip starts with. The dates have a fraction, like any
+// time.Now an issuer passes in.
+const (
+	interopIssuer  = "benchmark"
+	interopSubject = "user-42"
+)
+
+var (
+	interopAudience = []string{"api"}
+	interopIssued   = time.Unix(1600000000, 250000000)
+	interopExpires  = interopIssued.Add(time.Hour)
+)
+
+// interopAlg signs and verifies one algorithm in both libraries.
+type interopAlg struct {
+	name        string
+	oneSign     func(c *jwt1.Claims) ([]byte, error)
+	oneCheck    func(token []byte) (*jwt1.Claims, error)
+	twoSigner   jwt2.Signer
+	twoVerifier jwt2.Verifier
+}
+
+// interopKnown are the trips expected to fail, with the reason.
+var interopKnown = map[string]string{
+	"two->one " + jwt1.PS256 + "-2048-bit": "salt length",
+	"two->one " + jwt1.PS384 + "-2048-bit": "salt length",
+	"two->one " + jwt1.PS512 + "-2048-bit": "salt length",
+}
+
+// interopChanged are the claims a trip is known to change, keyed
+// "<trip> <claim>", with the reason. They show as changed in the matrix,
+// any other change fails.
+var interopChanged = map[string]string{
+	"one->two iat": "float64 seconds, the fraction reads back about 0.24µs early",
+	"one->two exp": "float64 seconds, the fraction reads back about 0.24µs early",
+	"two->one iat": "NumericDate is written as an integer, the fraction is lost",
+	"two->one exp": "NumericDate is written as an integer, the fraction is lost",
+}
+
+// interopClaims are the claims read on the verifying side, a zero date
+// is a missing one.
+type interopClaims struct {
+	iss, sub string
+	aud      []string
+	iat, exp time.Time
+}
+
+// interopDiff is a claim that did not come through as given.
+type interopDiff struct {
+	claim, got string
+}
+
+// TestInterop signs with one library and verifies with the other for
+// every algorithm. The claims are decoded on the verifying side and must
+// match the ones the signing library was given exactly, the dates to the
+// nanosecond. Changes listed in interopChanged show in the matrix, and
+// fail once the claim comes through unchanged.
+func TestInterop(t *testing.T) {
+	t.Logf("%-14s %-36s %-36s", "alg", "one->two", "two->one")
+	for _, alg := range interopAlgs(t) {
+		trips := []struct {
+			name  string
+			diffs []interopDiff
+			err   error
+		}{{name: "one->two"}, {name: "two->one"}}
+		var got interopClaims
+		got, trips[0].err = interopOneToTwo(t, alg)
+		trips[0].diffs = interopCompare(got)
+		got, trips[1].err = interopTwoToOne(t, alg)
+		trips[1].diffs = interopCompare(got)
+
+		row := fmt.Sprintf("%-14s", alg.name)
+		for _, trip := range trips {
+			row += fmt.Sprintf(" %-36s", interopCell(trip.err, trip.diffs))
+		}
+		t.Log(row)
+
+		for _, trip := range trips {
+			reason, known := interopKnown[trip.name+" "+alg.name]
+			switch {
+			case trip.err == nil && known:
+				t.Errorf("%s %s: works now, drop it from interopKnown", trip.name, alg.name)
+			case trip.err != nil && known:
+				t.Logf("%s %s: known failure, %s", trip.name, alg.name, reason)
+			case trip.err != nil:
+				t.Errorf("%s %s: %v", trip.name, alg.name, trip.err)
+			default:
+				interopCheckDiffs(t, trip.name, alg.name, trip.diffs)
+			}
+		}
+	}
+
+	var known []string
+	for key := range interopChanged {
+		known = append(known, key)
+	}
+	sort.Strings(known)
+	for _, key := range known {
+		t.Logf("%s: known change, %s", key, interopChanged[key])
+	}
+}
+
+// interopCheckDiffs fails on every change of a verified trip that is not
+// in interopChanged, and on every listed claim that came through as given.
+func interopCheckDiffs(t *testing.T, trip, alg string, diffs []interopDiff) {
+	changed := map[string]bool{}
+	for _, diff := range diffs {
+		changed[diff.claim] = true
+		if _, known := interopChanged[trip+" "+diff.claim]; !known {
+			t.Errorf("%s %s: changed %s", trip, alg, diff.got)
+		}
+	}
+	for _, claim := range []string{"iss", "sub", "aud", "iat", "exp"} {
+		if _, known := interopChanged[trip+" "+claim]; known && !changed[claim] {
+			t.Errorf("%s %s: %s comes through unchanged now, drop it from interopChanged", trip, alg, claim)
+		}
+	}
+}
+
+// interopCell is the matrix cell of a trip.
+func interopCell(err error, diffs []interopDiff) string {
+	if err != nil {
+		return err.Error()
+	}
+	if len(diffs) == 0 {
+		return "ok"
+	}
+	var changes []string
+	for _, diff := range diffs {
+		changes = append(changes, diff.got)
+	}
+	return "changed: " + strings.Join(changes, ", ")
+}
+
+func interopOneToTwo(t *testing.T, alg interopAlg) (interopClaims, error) {
+	claims := &jwt1.Claims{
+		Registered: jwt1.Registered{
+			Issuer:    interopIssuer,
+			Subject:   interopSubject,
+			Audiences: interopAudience,
+			Issued:    jwt1.NewNumericTime(interopIssued),
+			Expires:   jwt1.NewNumericTime(interopExpires),
+		},
+	}
+	token, err := alg.oneSign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tok, err := jwt2.ParseAndVerify(token, alg.twoVerifier)
+	if err != nil {
+		return interopClaims{}, fmt.Errorf("rejected: %w", err)
+	}
+	var got jwt2.StandardClaims
+	if err := json.Unmarshal(tok.RawClaims(), &got); err != nil {
+		return interopClaims{}, fmt.Errorf("decode: %w", err)
+	}
+	read := interopClaims{iss: got.Issuer, sub: got.Subject, aud: got.Audience}
+	if got.IssuedAt != nil {
+		read.iat = got.IssuedAt.Time
+	}
+	if got.ExpiresAt != nil {
+		read.exp = got.ExpiresAt.Time
+	}
+	return read, nil
+}
+
+func interopTwoToOne(t *testing.T, alg interopAlg) (interopClaims, error) {
+	claims := &jwt2.StandardClaims{
+		Issuer:    interopIssuer,
+		Subject:   interopSubject,
+		Audience:  interopAudience,
+		IssuedAt:  jwt2.NewNumericDate(interopIssued),
+		ExpiresAt: jwt2.NewNumericDate(interopExpires),
+	}
+	token, err := jwt2.NewBuilder(alg.twoSigner).BuildBytes(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := alg.oneCheck(token)
+	if err != nil {
+		return interopClaims{}, fmt.Errorf("rejected: %w", err)
+	}
+	read := interopClaims{iss: got.Issuer, sub: got.Subject, aud: got.Audiences}
+	if got.Issued != nil {
+		read.iat = got.Issued.Time()
+	}
+	if got.Expires != nil {
+		read.exp = got.Expires.Time()
+	}
+	return read, nil
+}
+
+// interopCompare lists the claims read on the verifying side that differ
+// from the ones the signer was given.
+func interopCompare(got interopClaims) []interopDiff {
+	var diffs []interopDiff
+	if got.iss != interopIssuer {
+		diffs = append(diffs, interopDiff{"iss", fmt.Sprintf("iss %q", got.iss)})
+	}
+	if got.sub != interopSubject {
+		diffs = append(diffs, interopDiff{"sub", fmt.Sprintf("sub %q", got.sub)})
+	}
+	if !reflect.DeepEqual(got.aud, interopAudience) {
+		diffs = append(diffs, interopDiff{"aud", fmt.Sprintf("aud %q", got.aud)})
+	}
+	for _, date := range []struct {
+		name      string
+		got, want time.Time
+	}{{"iat", got.iat, interopIssued}, {"exp", got.exp, interopExpires}} {
+		switch {
+		case date.got.IsZero():
+			diffs = append(diffs, interopDiff{date.name, date.name + " missing"})
+		case !date.got.Equal(date.want):
+			diffs = append(diffs, interopDiff{date.name, fmt.Sprintf("%s %s", date.name, date.got.Sub(date.want))})
+		}
+	}
+	return diffs
+}
+
+// interopAlgs pairs the tables of algs_test.go by name, every RS and PS
+// algorithm with the 2048-bit key.
+func interopAlgs(t *testing.T) []interopAlg {
+	keys := testKeysRSA([]int{2048})
+	rsaAlgs := []string{jwt1.RS256, jwt1.RS384, jwt1.RS512, jwt1.PS256, jwt1.PS384, jwt1.PS512}
+	ones := append(oneNonRSAAlgs(), oneRSAGrid(t, rsaAlgs, keys)...)
+
+	twos := map[string]twoAlg{}
+	for _, alg := range twoNonRSAAlgs(t) {
+		twos[alg.name] = alg
+	}
+	for _, alg := range rsaAlgs {
+		for _, two := range twoRSAGrid(t, []jwt2.Algorithm{jwt2.Algorithm(alg)}, keys) {
+			twos[two.name] = two
+		}
+	}
+
+	var algs []interopAlg
+	for _, one := range ones {
+		two, ok := twos[one.name]
+		if !ok {
+			t.Fatalf("%s: no jwt2 counterpart", one.name)
+		}
+		algs = append(algs, interopAlg{
+			name:        one.name,
+			oneSign:     one.sign,
+			oneCheck:    one.check,
+			twoSigner:   two.signer,
+			twoVerifier: two.verifier,
+		})
+	}
+	if len(algs) != len(twos) {
+		t.Fatalf("%d jwt1 algorithms for %d of jwt2", len(algs), len(twos))
+	}
+	return algs
+}
@@ -0,0 +1,444 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	jwt1 "github.com/pascaldekloe/jwt"
+
+	jwt2 "github.com/cristalhq/jwt/v3"
+)
+
+// jwksKey is a signing key as the issuer knows it.
+type jwksKey struct {
+	kid string
+	alg string
+	key interface{} // *ecdsa.PrivateKey, *rsa.PrivateKey or ed25519.PrivateKey
+}
+
+// jwksSets are the two generations the server rotates between.
+// ed-0 survives the rotation, the other keys are replaced.
+var jwksSets = [2][]jwksKey{
+	{
+		{"ec-0", "ES256", testKeyEC(256)},
+		{"rsa-0", "RS256", testKeyRSA(2048)},
+		{"ed-0", "EdDSA", testKeyEd25519()},
+	},
+	{
+		{"ec-1", "ES384", testKeyEC(384)},
+		{"rsa-1", "RS256", testKeyRSA(3072)},
+		{"ed-0", "EdDSA", testKeyEd25519()},
+	},
+}
+
+// jwksUnknown is never served, tokens with it always miss.
+var jwksUnknown = jwksKey{"gone", "ES512", testKeyEC(521)}
+
+// jwksServer stands in for a JWKS endpoint. It serves the public keys
+// of the current generation, rotate moves to the other one.
+type jwksServer struct {
+	*httptest.Server
+	gen     int32
+	fetches int32
+	bodies  [2][]byte
+}
+
+func newJWKSServer(tb testing.TB) *jwksServer {
+	s := &jwksServer{}
+	for i, set := range jwksSets {
+		var doc struct {
+			Keys []map[string]string `json:"keys"`
+		}
+		for _, k := range set {
+			doc.Keys = append(doc.Keys, jwkOf(k))
+		}
+		body, err := json.Marshal(doc)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		s.bodies[i] = body
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.fetches, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s.bodies[atomic.LoadInt32(&s.gen)])
+	}))
+	tb.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) rotate() {
+	atomic.StoreInt32(&s.gen, 1-atomic.LoadInt32(&s.gen))
+}
+
+func (s *jwksServer) current() []jwksKey {
+	return jwksSets[atomic.LoadInt32(&s.gen)]
+}
+
+func (s *jwksServer) fetch() ([]byte, error) {
+	resp, err := s.Client().Get(s.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+// jwkOf encodes the public half of k as in RFC 7517 and RFC 8037.
+func jwkOf(k jwksKey) map[string]string {
+	b64 := base64.RawURLEncoding.EncodeToString
+	jwk := map[string]string{"kid": k.kid, "alg": k.alg, "use": "sig"}
+	switch key := k.key.(type) {
+	case *ecdsa.PrivateKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk["kty"] = "EC"
+		jwk["crv"] = key.Curve.Params().Name
+		jwk["x"] = b64(key.X.FillBytes(make([]byte, size)))
+		jwk["y"] = b64(key.Y.FillBytes(make([]byte, size)))
+	case *rsa.PrivateKey:
+		jwk["kty"] = "RSA"
+		jwk["n"] = b64(key.N.Bytes())
+		jwk["e"] = b64(big.NewInt(int64(key.E)).Bytes())
+	case ed25519.PrivateKey:
+		jwk["kty"] = "OKP"
+		jwk["crv"] = "Ed25519"
+		jwk["x"] = b64(key.Public().(ed25519.PublicKey))
+	}
+	return jwk
+}
+
+// keySet verifies tokens against keys from a JWKS endpoint.
+type keySet interface {
+	refresh() error
+	check(token []byte) error
+}
+
+// oneKeySet keeps a jwt1.KeyRegister and the kids in it. Check falls back
+// to every key of the algorithm family when the kid is unknown, so the kid
+// is looked up first and an unknown one fetches the set again, as in
+// twoKeySet. A signature miss on a known kid is an error.
+type oneKeySet struct {
+	srv  *jwksServer
+	keys *jwt1.KeyRegister
+	kids map[string]bool
+}
+
+func (s *oneKeySet) refresh() error {
+	data, err := s.srv.fetch()
+	if err != nil {
+		return err
+	}
+	keys := new(jwt1.KeyRegister)
+	if _, err := keys.LoadJWK(data); err != nil {
+		return err
+	}
+	kids := make(map[string]bool)
+	for _, ids := range [][]string{keys.ECDSAIDs, keys.EdDSAIDs, keys.RSAIDs, keys.HMACIDs, keys.SecretIDs} {
+		for _, kid := range ids {
+			kids[kid] = true
+		}
+	}
+	s.keys, s.kids = keys, kids
+	return nil
+}
+
+func (s *oneKeySet) check(token []byte) error {
+	claims, err := jwt1.ParseWithoutCheck(token)
+	if err != nil {
+		return err
+	}
+	if !s.kids[claims.KeyID] {
+		if err := s.refresh(); err != nil {
+			return err
+		}
+		if !s.kids[claims.KeyID] {
+			return errUnknownKID
+		}
+	}
+	_, err = s.keys.Check(token)
+	return err
+}
+
+// twoKeySet maps every kid to a jwt2.Verifier, an unknown kid
+// fetches the set again.
+type twoKeySet struct {
+	srv       *jwksServer
+	verifiers map[string]jwt2.Verifier
+}
+
+var errUnknownKID = errors.New("unknown kid")
+
+func (s *twoKeySet) refresh() error {
+	data, err := s.srv.fetch()
+	if err != nil {
+		return err
+	}
+	verifiers, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+	s.verifiers = verifiers
+	return nil
+}
+
+// parseJWKS makes a verifier for every key in a JWK set, jwt2 has no JWK
+// support of its own. Every key needs an "alg" to pick the verifier.
+func parseJWKS(data []byte) (map[string]jwt2.Verifier, error) {
+	var doc struct {
+		Keys []struct {
+			Kty, Crv, Kid, Alg string
+			X, Y, N, E         string
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	verifiers := make(map[string]jwt2.Verifier, len(doc.Keys))
+	for _, k := range doc.Keys {
+		// the data comes from the endpoint, bad encodings are errors
+		raw := make(map[string][]byte, 4)
+		for name, s := range map[string]string{"x": k.X, "y": k.Y, "n": k.N, "e": k.E} {
+			b, err := base64.RawURLEncoding.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("kid %q: %s: %w", k.Kid, name, err)
+			}
+			raw[name] = b
+		}
+
+		var v jwt2.Verifier
+		var err error
+		alg := jwt2.Algorithm(k.Alg)
+		switch k.Kty {
+		case "EC":
+			curve := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}[k.Crv]
+			if curve == nil {
+				return nil, fmt.Errorf("kid %q: curve %q", k.Kid, k.Crv)
+			}
+			v, err = jwt2.NewVerifierES(alg, &ecdsa.PublicKey{
+				Curve: curve,
+				X:     new(big.Int).SetBytes(raw["x"]),
+				Y:     new(big.Int).SetBytes(raw["y"]),
+			})
+		case "RSA":
+			v, err = jwt2.NewVerifierRS(alg, &rsa.PublicKey{
+				N: new(big.Int).SetBytes(raw["n"]),
+				E: int(new(big.Int).SetBytes(raw["e"]).Int64()),
+			})
+		case "OKP":
+			v, err = jwt2.NewVerifierEdDSA(ed25519.PublicKey(raw["x"]))
+		default:
+			err = fmt.Errorf("kty %q", k.Kty)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("kid %q: %w", k.Kid, err)
+		}
+		verifiers[k.Kid] = v
+	}
+	return verifiers, nil
+}
+
+func (s *twoKeySet) check(token []byte) error {
+	tok, err := jwt2.Parse(token)
+	if err != nil {
+		return err
+	}
+	kid := tok.Header().KeyID
+	v, ok := s.verifiers[kid]
+	if !ok {
+		if err := s.refresh(); err != nil {
+			return err
+		}
+		if v, ok = s.verifiers[kid]; !ok {
+			return errUnknownKID
+		}
+	}
+	if tok.Header().Algorithm != v.Algorithm() {
+		return jwt2.ErrAlgorithmMismatch
+	}
+	return v.Verify(tok.Payload(), tok.Signature())
+}
+
+func oneJWKSToken(tb testing.TB, k jwksKey) []byte {
+	claims := &jwt1.Claims{Registered: benchClaims.Registered, KeyID: k.kid}
+	var token []byte
+	var err error
+	switch key := k.key.(type) {
+	case *ecdsa.PrivateKey:
+		token, err = claims.ECDSASign(k.alg, key)
+	case *rsa.PrivateKey:
+		token, err = claims.RSASign(k.alg, key)
+	case ed25519.PrivateKey:
+		token, err = claims.EdDSASign(key)
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return token
+}
+
+func twoJWKSToken(tb testing.TB, k jwksKey) []byte {
+	var signer jwt2.Signer
+	var err error
+	switch key := k.key.(type) {
+	case *ecdsa.PrivateKey:
+		signer, err = jwt2.NewSignerES(jwt2.Algorithm(k.alg), key)
+	case *rsa.PrivateKey:
+		signer, err = jwt2.NewSignerRS(jwt2.Algorithm(k.alg), key)
+	case ed25519.PrivateKey:
+		signer, err = jwt2.NewSignerEdDSA(key)
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+	token, err := jwt2.NewBuilder(signer, jwt2.WithKeyID(k.kid)).BuildBytes(mybenchClaims)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return token
+}
+
+var jwksLibs = []struct {
+	name   string
+	newSet func(srv *jwksServer) keySet
+	token  func(tb testing.TB, k jwksKey) []byte
+}{
+	{"one", func(srv *jwksServer) keySet { return &oneKeySet{srv: srv} }, oneJWKSToken},
+	{"two", func(srv *jwksServer) keySet { return &twoKeySet{srv: srv} }, twoJWKSToken},
+}
+
+// TestJWKSMalformed feeds parseJWKS bad key encodings, as a broken
+// endpoint could serve them. They must come back as errors.
+func TestJWKSMalformed(t *testing.T) {
+	for _, doc := range []string{
+		`{"keys":[{"kty":"RSA","kid":"k","alg":"RS256","n":"!!","e":"AQAB"}]}`,
+		`{"keys":[{"kty":"RSA","kid":"k","alg":"RS256","n":"AQAB","e":"A="}]}`,
+		`{"keys":[{"kty":"EC","kid":"k","alg":"ES256","crv":"P-256","x":"*","y":"AQAB"}]}`,
+		`{"keys":[{"kty":"OKP","kid":"k","alg":"EdDSA","crv":"Ed25519","x":"a b"}]}`,
+	} {
+		if _, err := parseJWKS([]byte(doc)); err == nil {
+			t.Errorf("%s: got no error", doc)
+		}
+	}
+}
+
+// TestJWKS verifies every kid before and after a rotation and checks
+// that each library fetches the set only for an unknown kid.
+func TestJWKS(t *testing.T) {
+	for _, lib := range jwksLibs {
+		t.Run(lib.name, func(t *testing.T) {
+			srv := newJWKSServer(t)
+			set := lib.newSet(srv)
+			if err := set.refresh(); err != nil {
+				t.Fatal(err)
+			}
+
+			// a known kid with a foreign key fails without a fetch
+			forged := jwksKey{srv.current()[0].kid, jwksUnknown.alg, jwksUnknown.key}
+			if err := set.check(lib.token(t, forged)); err == nil {
+				t.Error("accepted a token signed with a foreign key")
+			}
+			if got := atomic.LoadInt32(&srv.fetches); got != 1 {
+				t.Errorf("got %d fetches after a bad signature, want 1", got)
+			}
+
+			for round := 0; round < 2; round++ {
+				for _, k := range srv.current() {
+					if err := set.check(lib.token(t, k)); err != nil {
+						t.Errorf("round %d, kid %q: %v", round, k.kid, err)
+					}
+				}
+				srv.rotate()
+			}
+			if got := atomic.LoadInt32(&srv.fetches); got != 2 {
+				t.Errorf("got %d fetches for one rotation, want 2", got)
+			}
+
+			if err := set.check(lib.token(t, jwksUnknown)); err == nil {
+				t.Error("accepted a token with an unknown kid")
+			}
+		})
+	}
+}
+
+func Benchmark_One_JWKS(b *testing.B) {
+	benchJWKS(b, jwksLibs[0].newSet, jwksLibs[0].token)
+}
+
+func Benchmark_Two_JWKS(b *testing.B) {
+	benchJWKS(b, jwksLibs[1].newSet, jwksLibs[1].token)
+}
+
+func benchJWKS(b *testing.B, newSet func(srv *jwksServer) keySet, sign func(tb testing.TB, k jwksKey) []byte) {
+	var tokens [2][][]byte
+	for gen, keys := range jwksSets {
+		for _, k := range keys {
+			tokens[gen] = append(tokens[gen], sign(b, k))
+		}
+	}
+	unknown := sign(b, jwksUnknown)
+
+	// setup makes a server and a warm key set for every sub-benchmark
+	setup := func(b *testing.B) (*jwksServer, keySet) {
+		srv := newJWKSServer(b)
+		set := newSet(srv)
+		if err := set.refresh(); err != nil {
+			b.Fatal(err)
+		}
+		return srv, set
+	}
+	reportFetches := func(b *testing.B, srv *jwksServer) {
+		b.ReportMetric(float64(atomic.LoadInt32(&srv.fetches)-1)/float64(b.N), "fetches/op")
+	}
+
+	// tokens of every kid in the set, all in the cache
+	b.Run("hit-mixed", func(b *testing.B) {
+		srv, set := setup(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := set.check(tokens[0][i%len(tokens[0])]); err != nil {
+				b.Fatal(err)
+			}
+		}
+		reportFetches(b, srv)
+	})
+
+	// a kid the server does not have, every token costs a fetch
+	b.Run("miss-unknown", func(b *testing.B) {
+		srv, set := setup(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := set.check(unknown); err == nil {
+				b.Fatal("accepted a token with an unknown kid")
+			}
+		}
+		reportFetches(b, srv)
+	})
+
+	// the issuer rotates before every token, the first token
+	// with a new kid fetches the set and verifies
+	b.Run("rotate", func(b *testing.B) {
+		srv, set := setup(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			srv.rotate()
+			if err := set.check(tokens[atomic.LoadInt32(&srv.gen)][0]); err != nil {
+				b.Fatal(err)
+			}
+		}
+		reportFetches(b, srv)
+	})
+}
@@ -0,0 +1,297 @@
+//go:build ignore
+// +build ignore
+
+// Keygen writes the benchmark keys to testdata, named <family>-<bits>.pem
+// as the benchmarks look them up, and a self-signed certificate for each,
+// named <family>-<bits>-cert.pem. Every key and signature derives from a
+// fixed seed, so a run reproduces the files byte for byte:
+//
+//	go run keygen.go
+//	go run keygen.go -rsa 2048,6144 -ec P-256
+//
+// The standard library generators take no deterministic randomness, so the
+// keys are made here: a SHA-256 counter stream per key, a plain prime search
+// for RSA and a reduced scalar for EC. The ECDSA certificate signatures take
+// their nonce from a stream as well. That is fine for test fixtures only.
+package main
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	seedFlag    = flag.String("seed", "jwt-benchmark", "derivation `seed` for all keys")
+	dirFlag     = flag.String("dir", "testdata", "output `directory`")
+	ecFlag      = flag.String("ec", "P-256,P-384,P-521", "comma separated `curves`")
+	rsaFlag     = flag.String("rsa", "1024,2048,3072,4096,8192", "comma separated RSA key `bits`")
+	ed25519Flag = flag.Bool("ed25519", true, "write an Ed25519 key")
+)
+
+// curves are the ones crypto/ecdh has, it computes the public points.
+var curves = map[string]struct {
+	elliptic.Curve
+	ecdh ecdh.Curve
+}{
+	"P-256": {elliptic.P256(), ecdh.P256()},
+	"P-384": {elliptic.P384(), ecdh.P384()},
+	"P-521": {elliptic.P521(), ecdh.P521()},
+}
+
+func main() {
+	log.SetFlags(0)
+	flag.Parse()
+
+	for _, name := range strings.Split(*ecFlag, ",") {
+		if name == "" {
+			continue
+		}
+		curve, ok := curves[name]
+		if !ok {
+			log.Fatalf("keygen: unknown curve %q", name)
+		}
+		key := ecKey(name, newStream("ec-"+name))
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			log.Fatal("keygen: ", err)
+		}
+		name := fmt.Sprintf("ec-%d", curve.Params().BitSize)
+		write(name+".pem", "EC PRIVATE KEY", der)
+		writeCert(name, ecdsaSigner{key, newStream("cert-" + name)})
+	}
+
+	for _, s := range strings.Split(*rsaFlag, ",") {
+		if s == "" {
+			continue
+		}
+		bits, err := strconv.Atoi(s)
+		if err != nil || bits < 1024 || bits%16 != 0 {
+			log.Fatalf("keygen: RSA size %q not a multiple of 16 from 1024", s)
+		}
+		key := rsaKey(bits, newStream("rsa-"+s))
+		name := fmt.Sprintf("rsa-%d", bits)
+		write(name+".pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+		writeCert(name, key)
+	}
+
+	if *ed25519Flag {
+		seed := make([]byte, ed25519.SeedSize)
+		readFull(newStream("ed25519"), seed)
+		key := ed25519.NewKeyFromSeed(seed)
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			log.Fatal("keygen: ", err)
+		}
+		write("ed25519-256.pem", "PRIVATE KEY", der)
+		writeCert("ed25519-256", key)
+	}
+}
+
+// writeCert writes a certificate for key as handed out by a CA, with fixed
+// fields. RSA PKCS #1 v1.5 and Ed25519 signatures are deterministic, EC
+// keys come as an ecdsaSigner.
+func writeCert(name string, key crypto.Signer) {
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "benchmark"},
+		NotBefore:    time.Unix(1600000000, 0),
+		NotAfter:     time.Unix(1900000000, 0),
+	}
+	der, err := x509.CreateCertificate(newStream("cert-"+name), tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		log.Fatal("keygen: ", err)
+	}
+	write(name+"-cert.pem", "CERTIFICATE", der)
+}
+
+func write(name, typ string, der []byte) {
+	path := filepath.Join(*dirFlag, name)
+	err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0644)
+	if err != nil {
+		log.Fatal("keygen: ", err)
+	}
+	log.Print("keygen: wrote ", path)
+}
+
+// stream is a deterministic byte source, SHA-256 over the seed, a label
+// and a block counter. Keys get their own label, so adding or removing a
+// size leaves the other files as they are.
+type stream struct {
+	prefix  []byte
+	counter uint64
+	buf     []byte
+}
+
+func newStream(label string) *stream {
+	prefix := append([]byte(*seedFlag), 0)
+	return &stream{prefix: append(prefix, label...)}
+}
+
+func (s *stream) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		if len(s.buf) == 0 {
+			h := sha256.New()
+			h.Write(s.prefix)
+			var counter [8]byte
+			binary.BigEndian.PutUint64(counter[:], s.counter)
+			h.Write(counter[:])
+			s.counter++
+			s.buf = h.Sum(nil)
+		}
+		c := copy(p[n:], s.buf)
+		s.buf = s.buf[c:]
+		n += c
+	}
+	return n, nil
+}
+
+func readFull(r io.Reader, p []byte) {
+	if _, err := io.ReadFull(r, p); err != nil {
+		log.Fatal("keygen: ", err)
+	}
+}
+
+func ecKey(name string, r io.Reader) *ecdsa.PrivateKey {
+	curve := curves[name].Curve
+	d := ecScalar(curve, r)
+	key := &ecdsa.PrivateKey{D: d}
+	key.Curve = curve
+	key.X, key.Y = ecBaseMult(name, d)
+	return key
+}
+
+// ecBaseMult returns k times the base point of the named curve.
+func ecBaseMult(name string, k *big.Int) (x, y *big.Int) {
+	c := curves[name]
+	size := (c.Params().BitSize + 7) / 8
+	priv, err := c.ecdh.NewPrivateKey(k.FillBytes(make([]byte, size)))
+	if err != nil {
+		log.Fatal("keygen: ", err)
+	}
+	// uncompressed point, 0x04 followed by x and y
+	point := priv.PublicKey().Bytes()
+	return new(big.Int).SetBytes(point[1 : 1+size]), new(big.Int).SetBytes(point[1+size:])
+}
+
+// ecScalar takes 64 extra bits before the reduction mod N-1, which makes
+// the bias negligible, as in FIPS 186-4, appendix B.4.1.
+func ecScalar(curve elliptic.Curve, r io.Reader) *big.Int {
+	params := curve.Params()
+	b := make([]byte, (params.BitSize+64+7)/8)
+	readFull(r, b)
+
+	n := new(big.Int).Sub(params.N, big.NewInt(1))
+	d := new(big.Int).SetBytes(b)
+	d.Mod(d, n)
+	return d.Add(d, big.NewInt(1))
+}
+
+// ecdsaSigner signs with a nonce from r. The standard library ignores a
+// reader other than crypto/rand, so its signatures differ on every run.
+type ecdsaSigner struct {
+	key *ecdsa.PrivateKey
+	r   io.Reader
+}
+
+func (s ecdsaSigner) Public() crypto.PublicKey {
+	return &s.key.PublicKey
+}
+
+// Sign is ECDSA as in SEC 1, section 4.1.3, the digest is cut to the bit
+// length of N.
+func (s ecdsaSigner) Sign(_ io.Reader, digest []byte, _ crypto.SignerOpts) ([]byte, error) {
+	curve := s.key.Curve
+	n := curve.Params().N
+	if size := (n.BitLen() + 7) / 8; len(digest) > size {
+		digest = digest[:size]
+	}
+	e := new(big.Int).SetBytes(digest)
+	if excess := len(digest)*8 - n.BitLen(); excess > 0 {
+		e.Rsh(e, uint(excess))
+	}
+
+	for {
+		k := ecScalar(curve, s.r)
+		x, _ := ecBaseMult(curve.Params().Name, k)
+		r := new(big.Int).Mod(x, n)
+		if r.Sign() == 0 {
+			continue
+		}
+		sig := new(big.Int).Mul(r, s.key.D)
+		sig.Add(sig, e)
+		sig.Mul(sig, new(big.Int).ModInverse(k, n))
+		sig.Mod(sig, n)
+		if sig.Sign() == 0 {
+			continue
+		}
+		return asn1.Marshal(struct{ R, S *big.Int }{r, sig})
+	}
+}
+
+func rsaKey(bits int, r io.Reader) *rsa.PrivateKey {
+	e := big.NewInt(65537)
+	p := rsaPrime(bits/2, e, r)
+	q := rsaPrime(bits-bits/2, e, r)
+	for p.Cmp(q) == 0 {
+		q = rsaPrime(bits-bits/2, e, r)
+	}
+
+	one := big.NewInt(1)
+	pm1 := new(big.Int).Sub(p, one)
+	qm1 := new(big.Int).Sub(q, one)
+	phi := new(big.Int).Mul(pm1, qm1)
+
+	key := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: new(big.Int).Mul(p, q), E: int(e.Int64())},
+		D:         new(big.Int).ModInverse(e, phi),
+		Primes:    []*big.Int{p, q},
+	}
+	key.Precompute()
+	if err := key.Validate(); err != nil {
+		log.Fatalf("keygen: RSA %d-bit: %v", bits, err)
+	}
+	return key
+}
+
+// rsaPrime sets the two top bits of each candidate, so the product of
+// two primes has the full bit size.
+func rsaPrime(bits int, e *big.Int, r io.Reader) *big.Int {
+	b := make([]byte, (bits+7)/8)
+	one := big.NewInt(1)
+	for {
+		readFull(r, b)
+		b[0] &= 0xff >> uint(len(b)*8-bits)
+		p := new(big.Int).SetBytes(b)
+		p.SetBit(p, bits-1, 1)
+		p.SetBit(p, bits-2, 1)
+		p.SetBit(p, 0, 1)
+
+		pm1 := new(big.Int).Sub(p, one)
+		if new(big.Int).GCD(nil, nil, pm1, e).Cmp(one) != 0 {
+			continue
+		}
+		if p.ProbablyPrime(20) {
+			return p
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"testing"
+
+	jwt1 "github.com/pascaldekloe/jwt"
+
+	jwt2 "github.com/cristalhq/jwt/v3"
+)
+
+// keyFormat is an encoded key and the signing key it was made from.
+type keyFormat struct {
+	name string
+	kind string // "pem", "jwk" or "seed"
+	data []byte
+	key  jwksKey
+}
+
+func keyFormats(tb testing.TB) []keyFormat {
+	ec := jwksKey{"ec", "ES256", testKeyEC(256)}
+	rsaKey := jwksKey{"rsa", "RS256", testKeyRSA(2048)}
+	ed := jwksKey{"ed", "EdDSA", testKeyEd25519()}
+
+	pemOf := func(typ string, der []byte, err error) []byte {
+		if err != nil {
+			tb.Fatal(err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	}
+	jwkSetOf := func(k jwksKey) []byte {
+		data, err := json.Marshal(map[string]interface{}{
+			"keys": []map[string]string{jwkOf(k)},
+		})
+		if err != nil {
+			tb.Fatal(err)
+		}
+		return data
+	}
+
+	pkcs8 := func(key interface{}) []byte {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		return pemOf("PRIVATE KEY", der, err)
+	}
+	pkix := func(key interface{}) []byte {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		return pemOf("PUBLIC KEY", der, err)
+	}
+	sec1, err := x509.MarshalECPrivateKey(testKeyEC(256))
+
+	return []keyFormat{
+		{"pkcs8-ec", "pem", pkcs8(testKeyEC(256)), ec},
+		{"pkcs8-rsa", "pem", pkcs8(testKeyRSA(2048)), rsaKey},
+		{"pkcs8-ed25519", "pem", pkcs8(testKeyEd25519()), ed},
+		{"pkix-ec", "pem", pkix(&testKeyEC(256).PublicKey), ec},
+		{"pkix-rsa", "pem", pkix(&testKeyRSA(2048).PublicKey), rsaKey},
+		{"pkix-ed25519", "pem", pkix(testKeyEd25519Public()), ed},
+		{"x509-ec", "pem", testCert("ec", 256), ec},
+		{"x509-rsa", "pem", testCert("rsa", 2048), rsaKey},
+		{"x509-ed25519", "pem", testCert("ed25519", 256), ed},
+		{"sec1-ec", "pem", pemOf("EC PRIVATE KEY", sec1, err), ec},
+		{"pkcs1-rsa", "pem", pemOf("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(testKeyRSA(2048)), nil), rsaKey},
+		{"seed-ed25519", "seed", testKeyEd25519().Seed(), ed},
+		{"jwk-ec", "jwk", jwkSetOf(ec), ec},
+		{"jwk-rsa", "jwk", jwkSetOf(rsaKey), rsaKey},
+		{"jwk-ed25519", "jwk", jwkSetOf(ed), ed},
+	}
+}
+
+// oneLoadKey uses the KeyRegister loaders. jwt1 has no seed loader,
+// the stdlib makes the key and it is added to the register directly.
+func oneLoadKey(f keyFormat) (*jwt1.KeyRegister, error) {
+	var keys jwt1.KeyRegister
+	var n int
+	var err error
+	switch f.kind {
+	case "pem":
+		n, err = keys.LoadPEM(f.data, nil)
+	case "jwk":
+		n, err = keys.LoadJWK(f.data)
+	case "seed":
+		pub := ed25519.NewKeyFromSeed(f.data).Public().(ed25519.PublicKey)
+		keys.EdDSAs = append(keys.EdDSAs, pub)
+		n = 1
+	}
+	if err == nil && n != 1 {
+		err = fmt.Errorf("loaded %d keys", n)
+	}
+	return &keys, err
+}
+
+// twoLoadKey parses with the stdlib and makes a verifier,
+// jwt2 only takes parsed keys.
+func twoLoadKey(f keyFormat) (jwt2.Verifier, error) {
+	switch f.kind {
+	case "jwk":
+		verifiers, err := parseJWKS(f.data)
+		if err != nil {
+			return nil, err
+		}
+		verifier, ok := verifiers[f.key.kid]
+		if !ok {
+			return nil, fmt.Errorf("no key with kid %q", f.key.kid)
+		}
+		return verifier, nil
+	case "seed":
+		pub := ed25519.NewKeyFromSeed(f.data).Public().(ed25519.PublicKey)
+		return jwt2.NewVerifierEdDSA(pub)
+	}
+
+	block, _ := pem.Decode(f.data)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			key = cert.PublicKey
+		}
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		err = fmt.Errorf("PEM type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	alg := jwt2.Algorithm(f.key.alg)
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		return jwt2.NewVerifierES(alg, &key.PublicKey)
+	case *ecdsa.PublicKey:
+		return jwt2.NewVerifierES(alg, key)
+	case *rsa.PrivateKey:
+		return jwt2.NewVerifierRS(alg, &key.PublicKey)
+	case *rsa.PublicKey:
+		return jwt2.NewVerifierRS(alg, key)
+	case ed25519.PrivateKey:
+		return jwt2.NewVerifierEdDSA(key.Public().(ed25519.PublicKey))
+	case ed25519.PublicKey:
+		return jwt2.NewVerifierEdDSA(key)
+	default:
+		return nil, fmt.Errorf("key type %T", key)
+	}
+}
+
+// TestKeyFormats loads every format with both libraries and checks
+// that the loaded key verifies a token of the original key.
+func TestKeyFormats(t *testing.T) {
+	for _, f := range keyFormats(t) {
+		t.Run(f.name, func(t *testing.T) {
+			keys, err := oneLoadKey(f)
+			if err != nil {
+				t.Errorf("one: load: %v", err)
+			} else if _, err := keys.Check(oneJWKSToken(t, f.key)); err != nil {
+				t.Errorf("one: check: %v", err)
+			}
+
+			verifier, err := twoLoadKey(f)
+			if err != nil {
+				t.Errorf("two: load: %v", err)
+			} else if _, err := jwt2.ParseAndVerify(twoJWKSToken(t, f.key), verifier); err != nil {
+				t.Errorf("two: check: %v", err)
+			}
+		})
+	}
+}
+
+func Benchmark_One_LoadKey(b *testing.B) {
+	for _, f := range keyFormats(b) {
+		f := f
+		b.Run(f.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := oneLoadKey(f); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func Benchmark_Two_LoadKey(b *testing.B) {
+	for _, f := range keyFormats(b) {
+		f := f
+		b.Run(f.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := twoLoadKey(f); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package jwt_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jwt1 "github.com/pascaldekloe/jwt"
+
+	jwt2 "github.com/cristalhq/jwt/v3"
+)
+
+// claimsKey is the request context key of the verified claims.
+type claimsKey struct{}
+
+var errNoBearer = errors.New("no bearer token")
+
+// bearerToken returns the token of an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) ([]byte, error) {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return nil, errNoBearer
+	}
+	return []byte(auth[len(prefix):]), nil
+}
+
+func unauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	http.Error(w, err.Error(), http.StatusUnauthorized)
+}
+
+// oneMiddleware puts the *jwt1.Claims in the request context.
+func oneMiddleware(next http.Handler, check func(token []byte) (*jwt1.Claims, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := bearerToken(r)
+		if err != nil {
+			unauthorized(w, err)
+			return
+		}
+		claims, err := check(token)
+		if err != nil {
+			unauthorized(w, err)
+			return
+		}
+		if !claims.Valid(time.Now()) {
+			unauthorized(w, errors.New("token expired or not valid yet"))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+	})
+}
+
+// twoMiddleware puts the *jwt2.StandardClaims in the request context.
+func twoMiddleware(next http.Handler, verifier jwt2.Verifier) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := bearerToken(r)
+		if err != nil {
+			unauthorized(w, err)
+			return
+		}
+		tok, err := jwt2.ParseAndVerify(token, verifier)
+		if err != nil {
+			unauthorized(w, err)
+			return
+		}
+		claims := new(jwt2.StandardClaims)
+		if err := json.Unmarshal(tok.RawClaims(), claims); err != nil {
+			unauthorized(w, err)
+			return
+		}
+		if !claims.IsValidAt(time.Now()) {
+			unauthorized(w, errors.New("token expired or not valid yet"))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+	})
+}
+
+// middlewareTarget is the secured service. It greets the issuer from the
+// claims, or anyone without them, as the no-auth baseline.
+var middlewareTarget = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	iss := "anonymous"
+	switch claims := r.Context().Value(claimsKey{}).(type) {
+	case *jwt1.Claims:
+		iss = claims.Issuer
+	case *jwt2.StandardClaims:
+		iss = claims.Issuer
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("hello " + iss))
+})
+
+func newBearerRequest(token []byte) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if token != nil {
+		r.Header.Set("Authorization", "Bearer "+string(token))
+	}
+	return r
+}
+
+func TestMiddleware(t *testing.T) {
+	oneAlg := oneAlgs(t)[0]
+	oneToken, err := oneAlg.sign(&jwt1.Claims{Registered: benchClaims.Registered})
+	if err != nil {
+		t.Fatal(err)
+	}
+	twoAlg := twoAlgs(t)[0]
+	twoToken, err := jwt2.NewBuilder(twoAlg.signer).BuildBytes(mybenchClaims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handlers := map[string]struct {
+		h     http.Handler
+		token []byte
+	}{
+		"one": {oneMiddleware(middlewareTarget, oneAlg.check), oneToken},
+		"two": {twoMiddleware(middlewareTarget, twoAlg.verifier), twoToken},
+	}
+	for name, test := range handlers {
+		bad := append([]byte(nil), test.token...)
+		bad[len(bad)-2] ^= 'A' ^ 'B'
+
+		for _, c := range []struct {
+			name  string
+			token []byte
+			code  int
+			body  string
+		}{
+			{"valid", test.token, http.StatusOK, "hello benchmark"},
+			{"no-header", nil, http.StatusUnauthorized, errNoBearer.Error() + "\n"},
+			{"bad-signature", bad, http.StatusUnauthorized, ""},
+		} {
+			w := httptest.NewRecorder()
+			test.h.ServeHTTP(w, newBearerRequest(c.token))
+			if w.Code != c.code {
+				t.Errorf("%s %s: got status %d, want %d", name, c.name, w.Code, c.code)
+			}
+			if c.body != "" && w.Body.String() != c.body {
+				t.Errorf("%s %s: got body %q, want %q", name, c.name, w.Body, c.body)
+			}
+		}
+	}
+}
+
+// benchMiddleware serves r with a new recorder per request, as a server
+// would with a new connection state.
+func benchMiddleware(b *testing.B, h http.Handler, r *http.Request) {
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			b.Fatalf("got status %d: %s", w.Code, w.Body)
+		}
+	}
+}
+
+// Middleware benchmarks serve a request through the bearer middleware,
+// no-auth is the target alone. The difference is the per request cost of
+// authentication.
+
+func Benchmark_One_Middleware(b *testing.B) {
+	b.Run("no-auth", func(b *testing.B) {
+		benchMiddleware(b, middlewareTarget, newBearerRequest(nil))
+	})
+
+	for _, alg := range oneAlgs(b) {
+		token, err := alg.sign(&jwt1.Claims{Registered: benchClaims.Registered})
+		if err != nil {
+			b.Fatal(err)
+		}
+		h := oneMiddleware(middlewareTarget, alg.check)
+		b.Run(alg.name, func(b *testing.B) {
+			benchMiddleware(b, h, newBearerRequest(token))
+		})
+	}
+}
+
+func Benchmark_Two_Middleware(b *testing.B) {
+	b.Run("no-auth", func(b *testing.B) {
+		benchMiddleware(b, middlewareTarget, newBearerRequest(nil))
+	})
+
+	for _, alg := range twoAlgs(b) {
+		token, err := jwt2.NewBuilder(alg.signer).BuildBytes(mybenchClaims)
+		if err != nil {
+			b.Fatal(err)
+		}
+		h := twoMiddleware(middlewareTarget, alg.verifier)
+		b.Run(alg.name, func(b *testing.B) {
+			benchMiddleware(b, h, newBearerRequest(token))
+		})
+	}
+}
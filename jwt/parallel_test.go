@@ -0,0 +1,104 @@
+package jwt_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	jwt1 "github.com/pascaldekloe/jwt"
+
+	jwt2 "github.com/cristalhq/jwt/v3"
+)
+
+// Parallel benchmarks sign and check from all goroutines at once, ns/op
+// is the wall time per token of all of them. Run them at increasing
+// GOMAXPROCS on a machine with as many cores with:
+//
+//	go test -run ^$ -bench Parallel -cpu 1,2,4,8
+//
+// Shared state on these paths: jwt1.HMAC and the jwt2 HS signer and
+// verifier keep their hash in a sync.Pool, jwt1.HMACCheck makes a new
+// one per call. Neither library caches parsed keys globally, the keys
+// here are parsed once and only read.
+
+func Benchmark_One_Parallel(b *testing.B) {
+	for _, alg := range oneAllAlgs(b) {
+		alg := alg
+		b.Run("sign-"+alg.name, func(b *testing.B) {
+			benchParallel(b, func() func() error {
+				// Sign writes the Raw fields, every goroutine needs its own
+				claims := *benchClaims
+				return func() error {
+					_, err := alg.sign(&claims)
+					return err
+				}
+			})
+		})
+	}
+
+	for _, alg := range oneAllAlgs(b) {
+		alg := alg
+		token, err := alg.sign(&jwt1.Claims{Registered: benchClaims.Registered})
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run("check-"+alg.name, func(b *testing.B) {
+			benchParallel(b, func() func() error {
+				return func() error {
+					_, err := alg.check(token)
+					return err
+				}
+			})
+		})
+	}
+}
+
+func Benchmark_Two_Parallel(b *testing.B) {
+	for _, alg := range twoAllAlgs(b) {
+		bui := jwt2.NewBuilder(alg.signer)
+		b.Run("sign-"+alg.name, func(b *testing.B) {
+			benchParallel(b, func() func() error {
+				return func() error {
+					_, err := bui.BuildBytes(mybenchClaims)
+					return err
+				}
+			})
+		})
+	}
+
+	for _, alg := range twoAllAlgs(b) {
+		token, err := jwt2.NewBuilder(alg.signer).BuildBytes(mybenchClaims)
+		if err != nil {
+			b.Fatal(err)
+		}
+		verifier := alg.verifier
+		b.Run("check-"+alg.name, func(b *testing.B) {
+			benchParallel(b, func() func() error {
+				return func() error {
+					tok, err := jwt2.ParseAndVerify(token, verifier)
+					if err != nil {
+						return err
+					}
+					var claims jwt2.StandardClaims
+					return json.Unmarshal(tok.RawClaims(), &claims)
+				}
+			})
+		})
+	}
+}
+
+// benchParallel runs the func made by newWorker on every goroutine and
+// reports the tokens per second of all of them, over the timed loop only.
+func benchParallel(b *testing.B, newWorker func() func() error) {
+	start := time.Now()
+	b.RunParallel(func(pb *testing.PB) {
+		work := newWorker()
+		for pb.Next() {
+			if err := work(); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "tokens/s")
+}
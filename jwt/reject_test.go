@@ -0,0 +1,246 @@
+package jwt_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	jwt1 "github.com/pascaldekloe/jwt"
+
+	jwt2 "github.com/cristalhq/jwt/v3"
+)
+
+// rejectCase is junk an auth gateway gets, verified against HS256.
+// The cause is what is wrong with it, cases with a different cause
+// should get errors a caller can tell apart.
+type rejectCase struct {
+	name  string
+	cause string
+	token []byte
+}
+
+func rejectCases() []rejectCase {
+	valid := forgeToken(`{"alg":"HS256","typ":"JWT"}`, `{"iss":"benchmark"}`, sha256.New, securitySecret)
+	parts := strings.Split(valid, ".")
+	badSig := strings.Repeat("A", len(parts[2]))
+
+	// a well-formed header padded to size, the signature does not match
+	padded := func(size int) string {
+		header := `{"alg":"HS256","typ":"JWT","pad":"` + strings.Repeat("x", size) + `"}`
+		return encodeSegment(header) + "." + parts[1] + "." + badSig
+	}
+
+	return []rejectCase{
+		{"bad-base64", "encoding", []byte("!!!." + parts[1] + "." + parts[2])},
+		{"two-segments", "segments", []byte(parts[0] + "." + parts[1])},
+		{"four-segments", "segments", []byte(valid + "." + parts[2])},
+		{"header-4KB", "signature", []byte(padded(4 << 10))},
+		{"header-64KB", "signature", []byte(padded(64 << 10))},
+		{"invalid-json", "json", []byte(encodeSegment(`{"alg":"HS256"`) + "." + parts[1] + "." + parts[2])},
+		{"unknown-alg", "alg", []byte(forgeToken(`{"alg":"XX999","typ":"JWT"}`, `{"iss":"benchmark"}`, sha256.New, securitySecret))},
+		{"bad-signature", "signature", []byte(parts[0] + "." + parts[1] + "." + badSig)},
+	}
+}
+
+// rejectKnown are the junk tokens a library is known to accept, keyed
+// "<case> <lib>", with the reason.
+var rejectKnown = map[string]string{
+	"four-segments one": "anything after the third dot is ignored, the signed part is intact",
+}
+
+// rejectAmbiguous are the error classes a library is known to return for
+// more than one cause, keyed "<lib> <class>", with the reason.
+var rejectAmbiguous = map[string]string{
+	"one jwt1.ErrSigMiss":       "a missing signature segment reads as a signature mismatch",
+	"two jwt2.ErrInvalidFormat": "encoding, segment and JSON problems all get the same sentinel",
+}
+
+// rejectAllocSlack is how many more allocations per check header-64KB may
+// take than header-4KB. The header is decoded into one buffer, so the count
+// should not grow with its size.
+const rejectAllocSlack = 2
+
+// rejectClasses are the ways a caller can tell rejections apart.
+var rejectClasses = []struct {
+	name  string
+	match func(err error) bool
+}{
+	{"jwt1.ErrSigMiss", func(err error) bool { return errors.Is(err, jwt1.ErrSigMiss) }},
+	{"jwt1.AlgError", func(err error) bool {
+		var target jwt1.AlgError
+		return errors.As(err, &target)
+	}},
+	{"jwt2.ErrInvalidFormat", func(err error) bool { return errors.Is(err, jwt2.ErrInvalidFormat) }},
+	{"jwt2.ErrAlgorithmMismatch", func(err error) bool { return errors.Is(err, jwt2.ErrAlgorithmMismatch) }},
+	{"jwt2.ErrInvalidSignature", func(err error) bool { return errors.Is(err, jwt2.ErrInvalidSignature) }},
+	{"base64.CorruptInputError", func(err error) bool {
+		var target base64.CorruptInputError
+		return errors.As(err, &target)
+	}},
+	{"json.SyntaxError", func(err error) bool {
+		var target *json.SyntaxError
+		return errors.As(err, &target)
+	}},
+}
+
+func rejectClass(err error) string {
+	if err == nil {
+		return "accepted"
+	}
+	for _, c := range rejectClasses {
+		if c.match(err) {
+			return c.name
+		}
+	}
+	return ""
+}
+
+func oneRejectCheck(tb testing.TB) func(token []byte) error {
+	h, err := jwt1.NewHMAC(jwt1.HS256, securitySecret)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return func(token []byte) error {
+		_, err := h.Check(token)
+		return err
+	}
+}
+
+func twoRejectCheck(tb testing.TB) func(token []byte) error {
+	verifier, err := jwt2.NewVerifierHS(jwt2.HS256, securitySecret)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return func(token []byte) error {
+		_, err := jwt2.ParseAndVerify(token, verifier)
+		return err
+	}
+}
+
+// TestRejectErrors prints which error every junk token gets and fails
+// when a library returns an error that no sentinel or type matches, when
+// it accepts a token that is not in rejectKnown, or when it returns the
+// same class for different causes and that is not in rejectAmbiguous.
+// Known entries are reported, and fail once the library changes.
+func TestRejectErrors(t *testing.T) {
+	libs := []struct {
+		name  string
+		check func(token []byte) error
+	}{{"one", oneRejectCheck(t)}, {"two", twoRejectCheck(t)}}
+
+	// causes per library and class
+	causes := map[string]map[string]bool{}
+
+	t.Logf("%-14s %-26s %-26s", "case", "one", "two")
+	for _, c := range rejectCases() {
+		row := fmt.Sprintf("%-14s", c.name)
+		for _, lib := range libs {
+			err := lib.check(c.token)
+			class := rejectClass(err)
+			if class == "" {
+				t.Errorf("%s: %s returned an error no caller can match: %v", c.name, lib.name, err)
+				class = "?"
+			}
+			row += fmt.Sprintf(" %-26s", class)
+
+			key := c.name + " " + lib.name
+			reason, known := rejectKnown[key]
+			switch accepted := class == "accepted"; {
+			case accepted && known:
+				t.Logf("%s: known acceptance (%s)", key, reason)
+			case accepted:
+				t.Errorf("%s: accepted the token", key)
+			case known:
+				t.Errorf("%s: rejected now, drop it from rejectKnown", key)
+			default:
+				libClass := lib.name + " " + class
+				if causes[libClass] == nil {
+					causes[libClass] = map[string]bool{}
+				}
+				causes[libClass][c.cause] = true
+			}
+		}
+		t.Log(row)
+	}
+
+	for _, lib := range libs {
+		for _, c := range rejectClasses {
+			libClass := lib.name + " " + c.name
+			reason, known := rejectAmbiguous[libClass]
+			switch ambiguous := len(causes[libClass]) > 1; {
+			case ambiguous && known:
+				t.Logf("%s: known for causes %s (%s)", libClass, rejectCauses(causes[libClass]), reason)
+			case ambiguous:
+				t.Errorf("%s: returned for causes %s, a caller cannot tell them apart", libClass, rejectCauses(causes[libClass]))
+			case known:
+				t.Errorf("%s: one cause only now, drop it from rejectAmbiguous", libClass)
+			}
+		}
+	}
+}
+
+func rejectCauses(set map[string]bool) string {
+	var causes []string
+	for cause := range set {
+		causes = append(causes, cause)
+	}
+	sort.Strings(causes)
+	return strings.Join(causes, ", ")
+}
+
+// TestRejectAllocs fails when the allocations per check of header-64KB
+// exceed the ones of header-4KB by more than rejectAllocSlack. The bytes
+// per check are in the B/op of Benchmark_*_Reject.
+func TestRejectAllocs(t *testing.T) {
+	tokens := map[string][]byte{}
+	for _, c := range rejectCases() {
+		tokens[c.name] = c.token
+	}
+
+	for _, lib := range []struct {
+		name  string
+		check func(token []byte) error
+	}{{"one", oneRejectCheck(t)}, {"two", twoRejectCheck(t)}} {
+		allocs := func(name string) float64 {
+			return testing.AllocsPerRun(100, func() {
+				if lib.check(tokens[name]) == nil {
+					t.Fatalf("%s: %s accepted the token", lib.name, name)
+				}
+			})
+		}
+		small, large := allocs("header-4KB"), allocs("header-64KB")
+		t.Logf("%s: %.0f allocs for header-4KB, %.0f for header-64KB", lib.name, small, large)
+		if large > small+rejectAllocSlack {
+			t.Errorf("%s: header-64KB takes %.0f allocs, header-4KB %.0f, more than %d apart", lib.name, large, small, rejectAllocSlack)
+		}
+	}
+}
+
+func Benchmark_One_Reject(b *testing.B) {
+	benchReject(b, oneRejectCheck(b))
+}
+
+func Benchmark_Two_Reject(b *testing.B) {
+	benchReject(b, twoRejectCheck(b))
+}
+
+func benchReject(b *testing.B, check func(token []byte) error) {
+	for _, c := range rejectCases() {
+		b.Run(c.name, func(b *testing.B) {
+			if check(c.token) == nil {
+				b.Skip("token accepted")
+			}
+
+			for i := 0; i < b.N; i++ {
+				if check(c.token) == nil {
+					b.Fatal("token accepted")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package jwt_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	jwt1 "github.com/pascaldekloe/jwt"
+
+	jwt2 "github.com/cristalhq/jwt/v3"
+)
+
+// rfc7515Claims is the payload of RFC 7515 appendix A.1 to A.3,
+// it has CRLF line breaks, so neither library can encode it.
+const rfc7515Claims = "eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ"
+
+// rfcVector is a JWS from an RFC with the key to verify it.
+// Deterministic algorithms also get a jwt2 signer to reproduce it.
+type rfcVector struct {
+	name     string
+	token    string
+	oneCheck func(token []byte) (*jwt1.Claims, error)
+	verifier func(t *testing.T) jwt2.Verifier
+	signer   func(t *testing.T) jwt2.Signer
+}
+
+// rfcPlainPayload are the vectors whose payload is plain text, not a JSON
+// object. jwt1 verifies their signature and then fails to decode the claims.
+var rfcPlainPayload = map[string]bool{
+	"RFC7515-A.4-ES512": true,
+	"RFC8037-A.4-EdDSA": true,
+}
+
+var rfcVectors = []rfcVector{
+	{
+		name:  "RFC7515-A.1-HS256",
+		token: "eyJ0eXAiOiJKV1QiLA0KICJhbGciOiJIUzI1NiJ9." + rfc7515Claims + ".dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk",
+		oneCheck: func(token []byte) (*jwt1.Claims, error) {
+			return jwt1.HMACCheck(token, rfcHS256Key)
+		},
+		verifier: func(t *testing.T) jwt2.Verifier {
+			v, err := jwt2.NewVerifierHS(jwt2.HS256, rfcHS256Key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return v
+		},
+		signer: func(t *testing.T) jwt2.Signer {
+			s, err := jwt2.NewSignerHS(jwt2.HS256, rfcHS256Key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	},
+	{
+		name:  "RFC7515-A.2-RS256",
+		token: "eyJhbGciOiJSUzI1NiJ9." + rfc7515Claims + ".cC4hiUPoj9Eetdgtv3hF80EGrhuB__dzERat0XF9g2VtQgr9PJbu3XOiZj5RZmh7AAuHIm4Bh-0Qc_lF5YKt_O8W2Fp5jujGbds9uJdbF9CUAr7t1dnZcAcQjbKBYNX4BAynRFdiuB--f_nZLgrnbyTyWzO75vRK5h6xBArLIARNPvkSjtQBMHlb1L07Qe7K0GarZRmB_eSN9383LcOLn6_dO--xi12jzDwusC-eOkHWEsqtFZESc6BfI7noOPqvhJ1phCnvWh6IeYI2w9QOYEUipUTI8np6LbgGY9Fs98rqVt5AXLIhWkWywlVmtVrBp0igcN_IoypGlUPQGe77Rw",
+		oneCheck: func(token []byte) (*jwt1.Claims, error) {
+			return jwt1.RSACheck(token, &rfcRS256Key.PublicKey)
+		},
+		verifier: func(t *testing.T) jwt2.Verifier {
+			v, err := jwt2.NewVerifierRS(jwt2.RS256, &rfcRS256Key.PublicKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return v
+		},
+		signer: func(t *testing.T) jwt2.Signer {
+			s, err := jwt2.NewSignerRS(jwt2.RS256, rfcRS256Key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	},
+	{
+		name:  "RFC7515-A.3-ES256",
+		token: "eyJhbGciOiJFUzI1NiJ9." + rfc7515Claims + ".DtEhU3ljbEg8L38VWAfUAqOyKAM6-Xx-F4GawxaepmXFCgfTjDxw5djxLa8ISlSApmWQxfKTUJqPP3-Kg6NU1Q",
+		oneCheck: func(token []byte) (*jwt1.Claims, error) {
+			return jwt1.ECDSACheck(token, rfcES256Key)
+		},
+		verifier: func(t *testing.T) jwt2.Verifier {
+			v, err := jwt2.NewVerifierES(jwt2.ES256, rfcES256Key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return v
+		},
+	},
+	{
+		name:  "RFC7515-A.4-ES512",
+		token: "eyJhbGciOiJFUzUxMiJ9.UGF5bG9hZA.AdwMgeerwtHoh-l192l60hp9wAHZFVJbLfD_UxMi70cwnZOYaRI1bKPWROc-mZZqwqT2SI-KGDKB34XO0aw_7XdtAG8GaSwFKdCAPZgoXD2YBJZCPEX3xKpRwcdOO8KpEHwJjyqOgzDO7iKvU8vcnwNrmxYbSW9ERBXukOXolLzeO_Jn",
+		oneCheck: func(token []byte) (*jwt1.Claims, error) {
+			return jwt1.ECDSACheck(token, rfcES512Key)
+		},
+		verifier: func(t *testing.T) jwt2.Verifier {
+			v, err := jwt2.NewVerifierES(jwt2.ES512, rfcES512Key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return v
+		},
+	},
+	{
+		name:  "RFC8037-A.4-EdDSA",
+		token: "eyJhbGciOiJFZERTQSJ9.RXhhbXBsZSBvZiBFZDI1NTE5IHNpZ25pbmc.hgyY0il_MGCjP0JzlnLWG1PPOt7-09PGcvMg3AIbQR6dWbhijcNR4ki4iylGjg5BhVsPt9g7sVvpAr_MuM0KAg",
+		oneCheck: func(token []byte) (*jwt1.Claims, error) {
+			return jwt1.EdDSACheck(token, rfcEd25519Key.Public().(ed25519.PublicKey))
+		},
+		verifier: func(t *testing.T) jwt2.Verifier {
+			v, err := jwt2.NewVerifierEdDSA(rfcEd25519Key.Public().(ed25519.PublicKey))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return v
+		},
+		signer: func(t *testing.T) jwt2.Signer {
+			s, err := jwt2.NewSignerEdDSA(rfcEd25519Key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	},
+}
+
+// TestRFCVectors verifies the JWS examples of RFC 7515 and RFC 8037 with
+// both libraries, and reproduces the deterministic ones (HS256, RS256 and
+// EdDSA) byte for byte with jwt2.
+//
+// jwt1 only signs Claims, marshaled compact behind a header of its own, so
+// it cannot emit the CRLF JSON of RFC 7515 nor the plain text payloads of
+// RFC 7515, appendix A.4 and RFC 8037. It rejects those payloads with a
+// JSON syntax error after the signature passed, only the vectors listed in
+// rfcPlainPayload may get that error. jwt1 cannot reproduce any of the RFC
+// signatures, so it is not tried.
+func TestRFCVectors(t *testing.T) {
+	for _, v := range rfcVectors {
+		t.Run(v.name, func(t *testing.T) {
+			token := []byte(v.token)
+
+			_, err := v.oneCheck(token)
+			var syntaxErr *json.SyntaxError
+			switch {
+			case err == nil:
+				t.Log("one: verified")
+			case rfcPlainPayload[v.name] && errors.As(err, &syntaxErr):
+				t.Logf("one: signature verified, payload is not a JSON object: %v", err)
+			default:
+				t.Errorf("one: %v", err)
+			}
+
+			if _, err := jwt2.ParseAndVerify(token, v.verifier(t)); err != nil {
+				t.Errorf("two: %v", err)
+			} else {
+				t.Log("two: verified")
+			}
+
+			if v.signer == nil {
+				return
+			}
+			if rfcReproduce(t, v.signer(t), token) {
+				t.Log("two: reproduced byte for byte")
+			} else {
+				t.Error("two: signature differs from the RFC")
+			}
+		})
+	}
+}
+
+// rfcReproduce returns whether signer reproduces the signature of token.
+func rfcReproduce(t *testing.T, signer jwt2.Signer, token []byte) bool {
+	dot := bytes.LastIndexByte(token, '.')
+	sig, err := signer.Sign(token[:dot])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := base64.RawURLEncoding.DecodeString(string(token[dot+1:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig, want) {
+		t.Logf("got signature %x, want %x", sig, want)
+		return false
+	}
+	return true
+}
+
+// RFC 7515, appendix A.1 key
+var rfcHS256Key = mustDecodeB64("AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow")
+
+// RFC 7515, appendix A.2 key
+var rfcRS256Key = rfcRSAKey(map[string]string{
+	"n":  "ofgWCuLjybRlzo0tZWJjNiuSfb4p4fAkd_wWJcyQoTbji9k0l8W26mPddxHmfHQp-Vaw-4qPCJrcS2mJPMEzP1Pt0Bm4d4QlL-yRT-SFd2lZS-pCgNMsD1W_YpRPEwOWvG6b32690r2jZ47soMZo9wGzjb_7OMg0LOL-bSf63kpaSHSXndS5z5rexMdbBYUsLA9e-KXBdQOS-UTo7WTBEMa2R2CapHg665xsmtdVMTBQY4uDZlxvb3qCo5ZwKh9kG4LT6_I5IhlJH7aGhyxXFvUK-DWNmoudF8NAco9_h9iaGNj8q2ethFkMLs91kzk2PAcDTW9gb54h4FRWyuXpoQ",
+	"e":  "AQAB",
+	"d":  "Eq5xpGnNCivDflJsRQBXHx1hdR1k6Ulwe2JZD50LpXyWPEAeP88vLNO97IjlA7_GQ5sLKMgvfTeXZx9SE-7YwVol2NXOoAJe46sui395IW_GO-pWJ1O0BkTGoVEn2bKVRUCgu-GjBVaYLU6f3l9kJfFNS3E0QbVdxzubSu3Mkqzjkn439X0M_V51gfpRLI9JYanrC4D4qAdGcopV_0ZHHzQlBjudU2QvXt4ehNYTCBr6XCLQUShb1juUO1ZdiYoFaFQT5Tw8bGUl_x_jTj3ccPDVZFD9pIuhLhBOneufuBiB4cS98l2SR_RQyGWSeWjnczT0QU91p1DhOVRuOopznQ",
+	"p":  "4BzEEOtIpmVdVEZNCqS7baC4crd0pqnRH_5IB3jw3bcxGn6QLvnEtfdUdiYrqBdss1l58BQ3KhooKeQTa9AB0Hw_Py5PJdTJNPY8cQn7ouZ2KKDcmnPGBY5t7yLc1QlQ5xHdwW1VhvKn-nXqhJTBgIPgtldC-KDV5z-y2XDwGUc",
+	"q":  "uQPEfgmVtjL0Uyyx88GZFF1fOunH3-7cepKmtH4pxhtCoHqpWmT8YAmZxaewHgHAjLYsp1ZSe7zFYHj7C6ul7TjeLQeZD_YwD66t62wDmpe_HlB-TnBA-njbglfIsRLtXlnDzQkv5dTltRJ11BKBBypeeF6689rjcJIDEz9RWdc",
+	"dp": "BwKfV3Akq5_MFZDFZCnW-wzl-CCo83WoZvnLQwCTeDv8uzluRSnm71I3QCLdhrqE2e9YkxvuxdBfpT_PI7Yz-FOKnu1R6HsJeDCjn12Sk3vmAktV2zb34MCdy7cpdTh_YVr7tss2u6vneTwrA86rZtu5Mbr1C1XsmvkxHQAdYo0",
+	"dq": "h_96-mK1R_7glhsum81dZxjTnYynPbZpHziZjeeHcXYsXaaMwkOlODsWa7I9xXDoRwbKgB719rrmI2oKr6N3Do9U0ajaHF-NKJnwgjMd2w9cjz3_-kyNlxAr2v4IKhGNpmM5iIgOS1VZnOZ68m6_pbLBSp3nssTdlqvd0tIiTHU",
+	"qi": "IYd7DHOhrWvxkwPQsRM2tOgrjbcrfvtQJipd-DlcxyVuuM9sQLdgjVk2oy26F0EmpScGLq2MowX7fhd_QJQ3ydy5cY7YIBi87w93IKLEdfnbJtoOPLUW0ITrJReOgo1cq9SbsxYawBgfp_gh6A5603k2-ZQwVK0JKSHuLFkuQ3U",
+})
+
+// rfcRSAKey builds a private key from the JWK members of RFC 7518, section
+// 6.3.2. The CRT values are computed and must match the ones given.
+func rfcRSAKey(jwk map[string]string) *rsa.PrivateKey {
+	n := func(name string) *big.Int {
+		return new(big.Int).SetBytes(mustDecodeB64(jwk[name]))
+	}
+	key := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: n("n"), E: int(n("e").Int64())},
+		D:         n("d"),
+		Primes:    []*big.Int{n("p"), n("q")},
+	}
+	if err := key.Validate(); err != nil {
+		panic(err)
+	}
+	key.Precompute()
+	if key.Precomputed.Dp.Cmp(n("dp")) != 0 || key.Precomputed.Dq.Cmp(n("dq")) != 0 || key.Precomputed.Qinv.Cmp(n("qi")) != 0 {
+		panic("RSA JWK CRT values do not match the primes")
+	}
+	return key
+}
+
+// RFC 7515, appendix A.3 public key
+var rfcES256Key = &ecdsa.PublicKey{
+	Curve: elliptic.P256(),
+	X:     new(big.Int).SetBytes(mustDecodeB64("f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU")),
+	Y:     new(big.Int).SetBytes(mustDecodeB64("x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0")),
+}
+
+// RFC 7515, appendix A.4 public key
+var rfcES512Key = &ecdsa.PublicKey{
+	Curve: elliptic.P521(),
+	X:     new(big.Int).SetBytes(mustDecodeB64("AekpBQ8ST8a8VcfVOTNl353vSrDCLLJXmPk06wTjxrrjcBpXp5EOnYG_NjFZ6OvLFV1jSfS9tsz4qUxcWceqwQGk")),
+	Y:     new(big.Int).SetBytes(mustDecodeB64("ADSmRA43Z1DSNx_RvcLI87cdL07l6jQyyBXMoxVg_l2Th-x3S1WDhjDly79ajL4Kkd0AZMaZmh9ubmf63e3kyMj2")),
+}
+
+// RFC 8037, appendix A.1 key
+var rfcEd25519Key = ed25519.PrivateKey([]byte{
+	0x9d, 0x61, 0xb1, 0x9d, 0xef, 0xfd, 0x5a, 0x60,
+	0xba, 0x84, 0x4a, 0xf4, 0x92, 0xec, 0x2c, 0xc4,
+	0x44, 0x49, 0xc5, 0x69, 0x7b, 0x32, 0x69, 0x19,
+	0x70, 0x3b, 0xac, 0x03, 0x1c, 0xae, 0x7f, 0x60,
+	// public key suffix
+	0xd7, 0x5a, 0x98, 0x01, 0x82, 0xb1, 0x0a, 0xb7,
+	0xd5, 0x4b, 0xfe, 0xd3, 0xc9, 0x64, 0x07, 0x3a,
+	0x0e, 0xe1, 0x72, 0xf3, 0xda, 0xa6, 0x23, 0x25,
+	0xaf, 0x02, 0x1a, 0x68, 0xf7, 0x07, 0x51, 0x1a,
+})
+
+func mustDecodeB64(s string) []byte {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,223 @@
+package jwt_test
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"testing"
+
+	jwt1 "github.com/pascaldekloe/jwt"
+
+	jwt2 "github.com/cristalhq/jwt/v3"
+)
+
+var securitySecret = []byte("0123456789abcdef0123456789abcdef")
+
+// forgery is a token an attacker can make without the key.
+type forgery struct {
+	name  string
+	token string
+	// alg the verifier is set up for, "HS256" or "RS256"
+	alg string
+}
+
+// securityVerifier verifies token with a verifier pinned to alg
+// and returns the issuer of the accepted claims.
+type securityVerifier func(alg string, token []byte) (iss string, err error)
+
+// TestSecurity feeds forged and tampered tokens to both libraries.
+// Accepting any of them fails the test, the table is printed with -v.
+//
+// jwt1 has no RSA check pinned to a single algorithm, RSACheck takes any
+// of RSAAlgs by design, so the alg mismatch case uses HMAC where both
+// libraries are told the exact algorithm.
+func TestSecurity(t *testing.T) {
+	libs := []struct {
+		name   string
+		verify securityVerifier
+	}{
+		{"one", oneSecurityVerifier(t)},
+		{"two", twoSecurityVerifier(t)},
+	}
+
+	// the untouched token must pass, or every rejection below means nothing
+	valid := forgeToken(`{"alg":"HS256","typ":"JWT"}`, `{"iss":"benchmark"}`, sha256.New, securitySecret)
+	for _, lib := range libs {
+		if iss, err := lib.verify("HS256", []byte(valid)); err != nil || iss != "benchmark" {
+			t.Fatalf("%s: valid token got issuer %q and error %v", lib.name, iss, err)
+		}
+	}
+
+	t.Logf("%-24s %-24s %-24s", "case", "one", "two")
+	for _, f := range securityForgeries(t) {
+		row := fmt.Sprintf("%-24s", f.name)
+		for _, lib := range libs {
+			iss, err := lib.verify(f.alg, []byte(f.token))
+			if err != nil {
+				row += fmt.Sprintf(" %-24s", "pass: rejected")
+				continue
+			}
+			row += fmt.Sprintf(" %-24s", "FAIL: iss="+iss)
+			t.Errorf("%s %s: accepted the token with issuer %q", f.name, lib.name, iss)
+		}
+		t.Log(row)
+	}
+}
+
+func securityForgeries(t *testing.T) []forgery {
+	pubDER, err := x509.MarshalPKIXPublicKey(&testKeyRSA(2048).PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+
+	const claims = `{"iss":"benchmark"}`
+	valid := forgeToken(`{"alg":"HS256","typ":"JWT"}`, claims, sha256.New, securitySecret)
+	parts := strings.Split(valid, ".")
+
+	return []forgery{
+		{
+			name:  "alg-none-hmac",
+			token: forgeToken(`{"alg":"none","typ":"JWT"}`, claims, nil, nil),
+			alg:   "HS256",
+		},
+		{
+			name:  "alg-none-rsa",
+			token: forgeToken(`{"alg":"none","typ":"JWT"}`, claims, nil, nil),
+			alg:   "RS256",
+		},
+		{
+			name:  "alg-confusion",
+			token: forgeToken(`{"alg":"HS256","typ":"JWT"}`, claims, sha256.New, pubPEM),
+			alg:   "RS256",
+		},
+		{
+			name:  "alg-mismatch",
+			token: forgeToken(`{"alg":"HS512","typ":"JWT"}`, claims, sha512.New, securitySecret),
+			alg:   "HS256",
+		},
+		{
+			name:  "stripped-signature",
+			token: parts[0] + "." + parts[1] + ".",
+			alg:   "HS256",
+		},
+		{
+			name:  "no-signature-segment",
+			token: parts[0] + "." + parts[1],
+			alg:   "HS256",
+		},
+		{
+			name:  "modified-payload",
+			token: parts[0] + "." + encodeSegment(`{"iss":"attacker"}`) + "." + parts[2],
+			alg:   "HS256",
+		},
+	}
+}
+
+// duplicateMembers are tokens signed with the key that have a member twice,
+// so they are no forgery. RFC 7515, section 4 and RFC 7519, section 4 ask
+// to reject them or to take the last member. want is the issuer when the
+// last member is taken, empty when the last member has to fail the check.
+var duplicateMembers = []struct {
+	name   string
+	header string
+	claims string
+	want   string
+}{
+	{"header-alg", `{"alg":"HS256","alg":"none","typ":"JWT"}`, `{"iss":"benchmark"}`, ""},
+	{"claims-iss", `{"alg":"HS256","typ":"JWT"}`, `{"iss":"benchmark","iss":"attacker"}`, "attacker"},
+}
+
+// TestDuplicateMembers checks that both libraries reject every token of
+// duplicateMembers or take the last member, and that they agree, parsers
+// that pick different members read a different token.
+// Run with -v to see the table.
+func TestDuplicateMembers(t *testing.T) {
+	libs := []struct {
+		name   string
+		verify securityVerifier
+	}{
+		{"one", oneSecurityVerifier(t)},
+		{"two", twoSecurityVerifier(t)},
+	}
+
+	t.Logf("%-12s %-16s %-16s", "case", "one", "two")
+	for _, c := range duplicateMembers {
+		token := forgeToken(c.header, c.claims, sha256.New, securitySecret)
+		got := map[string]string{}
+		for _, lib := range libs {
+			iss, err := lib.verify("HS256", []byte(token))
+			switch {
+			case err != nil:
+				got[lib.name] = "rejected"
+			case c.want == "" || iss != c.want:
+				got[lib.name] = "iss=" + iss
+				t.Errorf("%s %s: accepted with issuer %q, not the last member", c.name, lib.name, iss)
+			default:
+				got[lib.name] = "iss=" + iss
+			}
+		}
+		t.Logf("%-12s %-16s %-16s", c.name, got["one"], got["two"])
+		if got["one"] != got["two"] {
+			t.Errorf("%s: one %s, two %s", c.name, got["one"], got["two"])
+		}
+	}
+}
+
+func oneSecurityVerifier(t *testing.T) securityVerifier {
+	hs256, err := jwt1.NewHMAC(jwt1.HS256, securitySecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func(alg string, token []byte) (string, error) {
+		var claims *jwt1.Claims
+		var err error
+		switch alg {
+		case "HS256":
+			claims, err = hs256.Check(token)
+		case "RS256":
+			claims, err = jwt1.RSACheck(token, &testKeyRSA(2048).PublicKey)
+		default:
+			t.Fatalf("no verifier for %s", alg)
+		}
+		if err != nil {
+			return "", err
+		}
+		return claims.Issuer, nil
+	}
+}
+
+func twoSecurityVerifier(t *testing.T) securityVerifier {
+	hs256, err := jwt2.NewVerifierHS(jwt2.HS256, securitySecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs256, err := jwt2.NewVerifierRS(jwt2.RS256, &testKeyRSA(2048).PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func(alg string, token []byte) (string, error) {
+		var verifier jwt2.Verifier
+		switch alg {
+		case "HS256":
+			verifier = hs256
+		case "RS256":
+			verifier = rs256
+		default:
+			t.Fatalf("no verifier for %s", alg)
+		}
+		tok, err := jwt2.ParseAndVerify(token, verifier)
+		if err != nil {
+			return "", err
+		}
+		var claims jwt2.StandardClaims
+		if err := json.Unmarshal(tok.RawClaims(), &claims); err != nil {
+			return "", err
+		}
+		return claims.Issuer, nil
+	}
+}
@@ -0,0 +1,166 @@
+package jwt_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	jwt1 "github.com/pascaldekloe/jwt"
+
+	jwt2 "github.com/cristalhq/jwt/v3"
+)
+
+// sizedClaims looks like the tokens of a multi-tenant API:
+// registered claims, tenant info, roles and a permission map.
+type sizedClaims struct {
+	jwt2.StandardClaims
+	Tenant      tenantInfo          `json:"tenant"`
+	Roles       []string            `json:"roles,omitempty"`
+	Permissions map[string][]string `json:"permissions,omitempty"`
+}
+
+type tenantInfo struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Region string `json:"region"`
+}
+
+// claimSizes are the target sizes of the JSON claims.
+var claimSizes = []struct {
+	name string
+	size int
+}{
+	{"100B", 100},
+	{"1KB", 1 << 10},
+	{"4KB", 4 << 10},
+	{"16KB", 16 << 10},
+}
+
+// newSizedClaims adds roles and permissions until the
+// JSON encoding reaches size bytes, it overshoots by less than an entry.
+func newSizedClaims(size int) *sizedClaims {
+	c := &sizedClaims{
+		StandardClaims: jwt2.StandardClaims{
+			Issuer:   "benchmark",
+			IssuedAt: jwt2.NewNumericDate(time.Now()),
+		},
+		Tenant: tenantInfo{ID: "t-42", Name: "acme", Region: "eu"},
+	}
+	for i := 0; len(mustMarshalJSON(c)) < size; i++ {
+		if i%2 == 0 {
+			c.Roles = append(c.Roles, fmt.Sprintf("role-%d", i))
+		} else {
+			if c.Permissions == nil {
+				c.Permissions = map[string][]string{}
+			}
+			c.Permissions[fmt.Sprintf("resource-%d", i)] = []string{"read", "write"}
+		}
+	}
+	return c
+}
+
+// oneSizedClaims turns c into the map form, the only way
+// jwt1 carries claims other than the registered ones.
+func oneSizedClaims(c *sizedClaims) *jwt1.Claims {
+	set := map[string]interface{}{}
+	if err := json.Unmarshal(mustMarshalJSON(c), &set); err != nil {
+		panic(err)
+	}
+	return &jwt1.Claims{
+		Registered: jwt1.Registered{
+			Issuer: c.Issuer,
+			Issued: jwt1.NewNumericTime(c.IssuedAt.Time),
+		},
+		Set: set,
+	}
+}
+
+// reportSizes sets the claims size for MB/s and reports the token parts,
+// as the other sign benchmarks do.
+func reportSizes(b *testing.B, claimsLen int, sizes *tokenSizes) {
+	b.SetBytes(int64(claimsLen))
+	sizes.report(b)
+}
+
+func Benchmark_One_Size(b *testing.B) {
+	algs := oneAllAlgs(b)
+	for _, size := range claimSizes {
+		claims := oneSizedClaims(newSizedClaims(size.size))
+		for _, alg := range algs {
+			token, err := alg.sign(claims)
+			if err != nil {
+				b.Fatal(err)
+			}
+			claimsLen := len(claims.Raw)
+
+			b.Run("sign-"+alg.name+"-"+size.name, func(b *testing.B) {
+				var sizes tokenSizes
+				for i := 0; i < b.N; i++ {
+					token, err := alg.sign(claims)
+					if err != nil {
+						b.Fatal(err)
+					}
+					sizes.add(token)
+				}
+				reportSizes(b, claimsLen, &sizes)
+			})
+
+			b.Run("check-"+alg.name+"-"+size.name, func(b *testing.B) {
+				var sizes tokenSizes
+				for i := 0; i < b.N; i++ {
+					_, err := alg.check(token)
+					if err != nil {
+						b.Fatal(err)
+					}
+					sizes.add(token)
+				}
+				reportSizes(b, claimsLen, &sizes)
+			})
+		}
+	}
+}
+
+func Benchmark_Two_Size(b *testing.B) {
+	algs := twoAllAlgs(b)
+	for _, size := range claimSizes {
+		claims := newSizedClaims(size.size)
+		claimsLen := len(mustMarshalJSON(claims))
+		for _, alg := range algs {
+			bui := jwt2.NewBuilder(alg.signer)
+			token, err := bui.BuildBytes(claims)
+			if err != nil {
+				b.Fatal(err)
+			}
+			verifier := alg.verifier
+
+			b.Run("sign-"+alg.name+"-"+size.name, func(b *testing.B) {
+				var sizes tokenSizes
+				for i := 0; i < b.N; i++ {
+					token, err := bui.BuildBytes(claims)
+					if err != nil {
+						b.Fatal(err)
+					}
+					sizes.add(token)
+				}
+				reportSizes(b, claimsLen, &sizes)
+			})
+
+			b.Run("check-"+alg.name+"-"+size.name, func(b *testing.B) {
+				var sizes tokenSizes
+				for i := 0; i < b.N; i++ {
+					tok, err := jwt2.ParseAndVerify(token, verifier)
+					if err != nil {
+						b.Fatal(err)
+					}
+					var res sizedClaims
+					if err := json.Unmarshal(tok.RawClaims(), &res); err != nil {
+						b.Fatal(err)
+					}
+					sizes.add(token)
+				}
+				reportSizes(b, claimsLen, &sizes)
+			})
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package jwt_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	jwt1 "github.com/pascaldekloe/jwt"
+
+	jwt2 "github.com/cristalhq/jwt/v3"
+)
+
+var (
+	timingFlag    = flag.Bool("timing", false, "run the constant-time checks of TestConstantTime")
+	timingSamples = flag.Int("timing.samples", 200000, "measurements per signature class in TestConstantTime")
+)
+
+// timingThreshold is the |t| from which dudect calls it a leak.
+const timingThreshold = 4.5
+
+// timingCrop drops the slowest measurements, which are mostly
+// scheduler and GC noise, before the t-test.
+const timingCrop = 0.9
+
+// timingTarget verifies a token that has a bad signature.
+type timingTarget struct {
+	name    string
+	check   func(token []byte) error
+	control bool // not constant-time on purpose
+}
+
+// TestConstantTime is a dudect-style check on the HMAC signature compare.
+// It times tokens whose signature differs from the valid one in the first
+// byte against those that differ in the last byte, in random order, and
+// runs Welch's t-test on the two classes. An early exit compare shows up
+// as a large t. The control target has one, the test fails when it goes
+// undetected, as the verdicts of the libraries would mean nothing then.
+//
+// It takes a while and wants an idle machine, so it only runs with:
+//
+//	go test -run TestConstantTime -v -timing
+func TestConstantTime(t *testing.T) {
+	if !*timingFlag {
+		t.Skip("enable with -timing")
+	}
+
+	secret := hmacSecrets()[1]
+	token, err := benchClaims.HMACSign(jwt1.HS256, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := jwt2.NewVerifierHS(jwt2.HS256, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, last := timingTokens(t, token)
+
+	// the control goes first, without a leak there the others mean nothing
+	targets := []timingTarget{
+		{name: "control", control: true, check: func(bad []byte) error {
+			return earlyExitCompare(bad, token)
+		}},
+		{name: "one", check: func(token []byte) error {
+			_, err := jwt1.HMACCheck(token, secret)
+			return err
+		}},
+		{name: "two", check: func(token []byte) error {
+			_, err := jwt2.ParseAndVerify(token, verifier)
+			return err
+		}},
+	}
+
+	t.Logf("%-8s %10s %10s %8s  %s", "target", "first ns", "last ns", "t", "verdict")
+	for _, target := range targets {
+		if target.check(first) == nil || target.check(last) == nil {
+			t.Fatalf("%s: accepted a bad signature", target.name)
+		}
+		a, b := timingMeasure(target.check, first, last, *timingSamples)
+		tv := welchT(a, b)
+
+		verdict := "no leak"
+		if math.Abs(tv) > timingThreshold {
+			verdict = "leak"
+		}
+		t.Logf("%-8s %10.1f %10.1f %8.2f  %s", target.name, mean(a), mean(b), tv, verdict)
+		switch {
+		case target.control && verdict != "leak":
+			t.Fatalf("%s: |t| = %.2f within %.1f, the harness does not detect the early exit", target.name, math.Abs(tv), timingThreshold)
+		case !target.control && verdict == "leak":
+			t.Errorf("%s: |t| = %.2f exceeds %.1f, signature compare leaks timing", target.name, math.Abs(tv), timingThreshold)
+		}
+	}
+}
+
+// timingTokens returns token with the first and with the last signature
+// byte flipped.
+func timingTokens(t *testing.T, token []byte) (first, last []byte) {
+	dot := len(token)
+	for token[dot-1] != '.' {
+		dot--
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(string(token[dot:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flip := func(i int) []byte {
+		bad := append([]byte(nil), sig...)
+		bad[i] ^= 0x01
+		return append(append([]byte(nil), token[:dot]...), base64.RawURLEncoding.EncodeToString(bad)...)
+	}
+	return flip(0), flip(len(sig) - 1)
+}
+
+// timingMeasure times n checks of each token, interleaved at random so
+// drift in the machine hits both classes alike. Both classes are cropped
+// at the same duration.
+func timingMeasure(check func([]byte) error, first, last []byte, n int) (a, b []float64) {
+	// warm up pools and caches
+	for i := 0; i < 1000; i++ {
+		check(first)
+		check(last)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	a = make([]float64, 0, n)
+	b = make([]float64, 0, n)
+	for len(a) < n || len(b) < n {
+		firstClass := r.Intn(2) == 0
+		if firstClass && len(a) == n || !firstClass && len(b) == n {
+			continue
+		}
+		token := last
+		if firstClass {
+			token = first
+		}
+
+		start := time.Now()
+		check(token)
+		d := float64(time.Since(start))
+
+		if firstClass {
+			a = append(a, d)
+		} else {
+			b = append(b, d)
+		}
+	}
+
+	all := append(append([]float64(nil), a...), b...)
+	sort.Float64s(all)
+	limit := all[int(float64(len(all)-1)*timingCrop)]
+	return crop(a, limit), crop(b, limit)
+}
+
+func crop(samples []float64, limit float64) []float64 {
+	var kept []float64
+	for _, s := range samples {
+		if s <= limit {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+// welchT is the t statistic of Welch's unequal variances t-test.
+func welchT(a, b []float64) float64 {
+	ma, mb := mean(a), mean(b)
+	va, vb := variance(a, ma), variance(b, mb)
+	return (ma - mb) / math.Sqrt(va/float64(len(a))+vb/float64(len(b)))
+}
+
+func mean(samples []float64) float64 {
+	var sum float64
+	for _, s := range samples {
+		sum += s
+	}
+	return sum / float64(len(samples))
+}
+
+func variance(samples []float64, mean float64) float64 {
+	var sum float64
+	for _, s := range samples {
+		sum += (s - mean) * (s - mean)
+	}
+	return sum / float64(len(samples)-1)
+}
+
+var errSigMismatch = errors.New("signature mismatch")
+
+// earlyExitCompare decodes the signature of bad and compares it to the
+// one of good byte by byte, returning at the first difference.
+func earlyExitCompare(bad, good []byte) error {
+	split := func(token []byte) []byte {
+		dot := len(token)
+		for token[dot-1] != '.' {
+			dot--
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(string(token[dot:]))
+		return sig
+	}
+	x, y := split(bad), split(good)
+	for i := range x {
+		if x[i] != y[i] {
+			return errSigMismatch
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package jwt_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	jwt1 "github.com/pascaldekloe/jwt"
+
+	jwt2 "github.com/cristalhq/jwt/v3"
+)
+
+// validateNow is the moment all the validation cases are checked at.
+var validateNow = time.Unix(1600000000, 0)
+
+// validateLeeway is the clock skew allowed between issuer and gateway.
+const validateLeeway = time.Minute
+
+const (
+	validateIssuer   = "benchmark"
+	validateAudience = "api"
+)
+
+// validateCases are registered claims relative to validateNow
+// and the decision an API gateway has to make for them.
+var validateCases = []struct {
+	name  string
+	iss   string
+	aud   string
+	iat   time.Duration
+	nbf   time.Duration
+	exp   time.Duration
+	valid bool
+}{
+	{"valid", validateIssuer, validateAudience, -10 * time.Minute, -10 * time.Minute, 10 * time.Minute, true},
+	{"expired", validateIssuer, validateAudience, -2 * time.Hour, -2 * time.Hour, -time.Hour, false},
+	{"not-yet-valid", validateIssuer, validateAudience, -10 * time.Minute, time.Hour, 2 * time.Hour, false},
+	{"expired-in-leeway", validateIssuer, validateAudience, -time.Hour, -time.Hour, -30 * time.Second, true},
+	{"issued-in-future", validateIssuer, validateAudience, time.Hour, -10 * time.Minute, 2 * time.Hour, false},
+	{"wrong-issuer", "someone", validateAudience, -10 * time.Minute, -10 * time.Minute, 10 * time.Minute, false},
+	{"wrong-audience", validateIssuer, "web", -10 * time.Minute, -10 * time.Minute, 10 * time.Minute, false},
+}
+
+// oneValidate checks claims with Registered.Valid and AcceptAudience.
+// Valid has no leeway, so expiry and not-before are checked at shifted
+// times. It does not look at the issue time, that part is done by hand.
+func oneValidate(c *jwt1.Claims, now time.Time) bool {
+	exp := jwt1.Registered{Expires: c.Expires}
+	nbf := jwt1.Registered{NotBefore: c.NotBefore}
+	return exp.Valid(now.Add(-validateLeeway)) &&
+		nbf.Valid(now.Add(validateLeeway)) &&
+		(c.Issued == nil || !c.Issued.Time().After(now.Add(validateLeeway))) &&
+		c.Issuer == validateIssuer &&
+		c.AcceptAudience(validateAudience)
+}
+
+// twoValidate checks claims with the StandardClaims helpers.
+func twoValidate(c *jwt2.StandardClaims, now time.Time) bool {
+	return c.IsValidExpiresAt(now.Add(-validateLeeway)) &&
+		c.IsValidNotBefore(now.Add(validateLeeway)) &&
+		c.IsValidIssuedAt(now.Add(validateLeeway)) &&
+		c.IsIssuer(validateIssuer) &&
+		c.IsForAudience(validateAudience)
+}
+
+func oneValidateToken(token []byte) (bool, error) {
+	claims, err := jwt1.HMACCheck(token, testSecret)
+	if err != nil {
+		return false, err
+	}
+	return oneValidate(claims, validateNow), nil
+}
+
+func twoValidateToken(verifier jwt2.Verifier, token []byte) (bool, error) {
+	tok, err := jwt2.ParseAndVerify(token, verifier)
+	if err != nil {
+		return false, err
+	}
+	var claims jwt2.StandardClaims
+	if err := json.Unmarshal(tok.RawClaims(), &claims); err != nil {
+		return false, err
+	}
+	return twoValidate(&claims, validateNow), nil
+}
+
+func oneValidateTokens(tb testing.TB) [][]byte {
+	tokens := make([][]byte, len(validateCases))
+	for i, c := range validateCases {
+		claims := &jwt1.Claims{
+			Registered: jwt1.Registered{
+				Issuer:    c.iss,
+				Audiences: []string{c.aud},
+				Issued:    jwt1.NewNumericTime(validateNow.Add(c.iat)),
+				NotBefore: jwt1.NewNumericTime(validateNow.Add(c.nbf)),
+				Expires:   jwt1.NewNumericTime(validateNow.Add(c.exp)),
+			},
+		}
+		token, err := claims.HMACSign(jwt1.HS256, testSecret)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		tokens[i] = token
+	}
+	return tokens
+}
+
+func twoValidateTokens(tb testing.TB) ([][]byte, jwt2.Verifier) {
+	signer, err := jwt2.NewSignerHS(jwt2.HS256, testSecret)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	verifier, err := jwt2.NewVerifierHS(jwt2.HS256, testSecret)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	bui := jwt2.NewBuilder(signer)
+	tokens := make([][]byte, len(validateCases))
+	for i, c := range validateCases {
+		claims := &jwt2.StandardClaims{
+			Issuer:    c.iss,
+			Audience:  jwt2.Audience{c.aud},
+			IssuedAt:  jwt2.NewNumericDate(validateNow.Add(c.iat)),
+			NotBefore: jwt2.NewNumericDate(validateNow.Add(c.nbf)),
+			ExpiresAt: jwt2.NewNumericDate(validateNow.Add(c.exp)),
+		}
+		token, err := bui.BuildBytes(claims)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		tokens[i] = token
+	}
+	return tokens, verifier
+}
+
+// TestClaimsValidation confirms that both libraries
+// reach the expected decision for every case.
+func TestClaimsValidation(t *testing.T) {
+	oneTokens := oneValidateTokens(t)
+	twoTokens, verifier := twoValidateTokens(t)
+
+	t.Logf("%-18s %-6s %-6s %-6s", "case", "want", "one", "two")
+	for i, c := range validateCases {
+		one, err := oneValidateToken(oneTokens[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		two, err := twoValidateToken(verifier, twoTokens[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Logf("%-18s %-6t %-6t %-6t", c.name, c.valid, one, two)
+		if one != c.valid || two != c.valid {
+			t.Errorf("%s: want %t, got one %t and two %t", c.name, c.valid, one, two)
+		}
+	}
+}
+
+func Benchmark_One_Validate(b *testing.B) {
+	tokens := oneValidateTokens(b)
+	for i, c := range validateCases {
+		token, want := tokens[i], c.valid
+		b.Run(c.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				valid, err := oneValidateToken(token)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if valid != want {
+					b.Fatalf("got %t, want %t", valid, want)
+				}
+			}
+		})
+	}
+}
+
+func Benchmark_Two_Validate(b *testing.B) {
+	tokens, verifier := twoValidateTokens(b)
+	for i, c := range validateCases {
+		token, want := tokens[i], c.valid
+		b.Run(c.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				valid, err := twoValidateToken(verifier, token)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if valid != want {
+					b.Fatalf("got %t, want %t", valid, want)
+				}
+			}
+		})
+	}
+}